@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"sort"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -39,6 +41,16 @@ const (
 	RetainOnCleanup DeletionPolicy = "Retain"
 )
 
+type TargetTrackingMode string
+
+const (
+	// TrackTargetsInStatus keeps the secret references and target states in the GeneratedSecret status
+	TrackTargetsInStatus TargetTrackingMode = "Status"
+	// TrackTargetsInObjects keeps the secret references and target states in GeneratedSecretTarget objects,
+	// the GeneratedSecret status then only holds aggregates. Intended for secrets spanning many namespaces.
+	TrackTargetsInObjects TargetTrackingMode = "Objects"
+)
+
 // GeneratedSecretSpec defines the desired state of Secret
 type GeneratedSecretSpec struct {
 	// SecretType holds the type of secret being generated
@@ -51,10 +63,24 @@ type GeneratedSecretSpec struct {
 	// +optional
 	Template SecretTemplate `json:"template"`
 
-	// DeletionPolicy is the policy to be used when the secret is deleted
+	// DeletionPolicy is the policy to be used when the secret is deleted. Stale secrets are always retained
 	// +kubebuilder:default="Delete"
 	// +optional
 	DeletionPolicy DeletionPolicy `json:"deletionPolicy"`
+
+	// TargetTracking defines where the state of the individual target secrets is tracked
+	// +kubebuilder:validation:Enum=Status;Objects
+	// +kubebuilder:default="Status"
+	// +optional
+	TargetTracking TargetTrackingMode `json:"targetTracking,omitempty"`
+
+	// Targets lists namespaces whose secret differs from the shared one. Namespaces listed here are targets as well,
+	// next to the namespaces in Metadata. Values that are not overridden are shared with all other targets.
+	// +optional
+	// +listType=map
+	// +listMapKey=namespace
+	Targets []TargetSpec `json:"targets,omitempty"`
+
 	// // SecretRef is an reference to a kubernetes secret that will be created
 	// SecretRef *corev1.SecretReference `json:"passwordSecretRef,omitempty"`
 }
@@ -96,8 +122,59 @@ func (meta *SecretMetadata) GetAnnotations() map[string]string {
 	return meta.Annotations
 }
 
+// TargetSpec overrides the secret in a single target namespace
+type TargetSpec struct {
+	// Namespace of the target secret
+	Namespace string `json:"namespace"`
+
+	// Name overrides the name of the secret in this namespace
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Labels are added to the labels of the secret in this namespace, and take precedence over the shared labels
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the annotations of the secret in this namespace, and take precedence over the shared annotations
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Data overrides individual template items in this namespace. Templated values referring to an overridden
+	// key through .Self are rendered for this namespace as well, all other values are shared.
+	// +optional
+	Data SecretValueItems `json:"data,omitempty"`
+}
+
 type SecretTemplate struct {
+	// +optional
 	Data SecretValueItems `json:"data"`
+
+	// Document is a template that renders a single YAML or JSON document, whose top-level keys become keys of the secret.
+	// It is rendered after all data items, which are available as .Self. Its keys cannot be defined in data as well.
+	// +optional
+	Document *DocumentTemplateSpec `json:"document,omitempty"`
+}
+
+// DocumentFormat is the format of a rendered document template
+type DocumentFormat string
+
+const (
+	// DocumentFormatYAML parses the document as YAML, nested values are stored as YAML
+	DocumentFormatYAML DocumentFormat = "YAML"
+	// DocumentFormatJSON parses the document as JSON, nested values are stored as JSON
+	DocumentFormatJSON DocumentFormat = "JSON"
+)
+
+// DocumentTemplateSpec is a template that renders several keys of the secret at once. Strings, numbers and booleans
+// are stored as written, e.g. 0123 stays 0123, and nested lists and mappings are encoded in the format of the document.
+type DocumentTemplateSpec struct {
+	TemplatedValueSpec `json:",inline"`
+
+	// Format of the rendered document
+	// +kubebuilder:validation:Enum=YAML;JSON
+	// +kubebuilder:default=YAML
+	// +optional
+	Format DocumentFormat `json:"format,omitempty"`
 }
 
 type SecretValueItems map[string]SecretValueItemTemplate
@@ -119,6 +196,129 @@ type SecretValueItemTemplate struct {
 	// Generated value is a value that will be generated using a random string generator
 	// +optional
 	Generated *GeneratedValueSpec `json:"generated,omitempty"`
+
+	// Passphrase value is a passphrase of random words, for credentials that are typed by humans
+	// +optional
+	Passphrase *PassphraseValueSpec `json:"passphrase,omitempty"`
+
+	// Pattern value is a random value of a predictable shape, e.g. for usernames and identifiers
+	// +optional
+	Pattern *PatternValueSpec `json:"pattern,omitempty"`
+
+	// Hashed value is a password hash of another key of the same secret, e.g. for the server side of a generated password
+	// +optional
+	Hashed *HashedValueSpec `json:"hashed,omitempty"`
+
+	// Htpasswd value is an htpasswd file with a bcrypt hash of a generated password for each user, e.g. for basic auth
+	// of an ingress. The plaintext password of each user is stored in its own key, see HtpasswdValueSpec.PasswordKeyPrefix.
+	// +optional
+	Htpasswd *HtpasswdValueSpec `json:"htpasswd,omitempty"`
+
+	// DockerConfig value is a docker config JSON with the credentials of a registry. Stored in the .dockerconfigjson key,
+	// the target secrets are of type kubernetes.io/dockerconfigjson unless the type is set in the metadata.
+	// +optional
+	DockerConfig *DockerConfigValueSpec `json:"dockerConfig,omitempty"`
+}
+
+// DockerConfigValueSpec builds a docker config JSON for a registry, with the password held by another key of the
+// same secret, such as a generated value or a value templated from an input secret
+type DockerConfigValueSpec struct {
+	// Registry is the server of the registry, e.g. registry.example.com:5000
+	Registry string `json:"registry"`
+	// Username to authenticate to the registry with
+	Username string `json:"username"`
+	// PasswordKey is the key of the same secret that holds the password
+	PasswordKey string `json:"passwordKey"`
+	// Email of the user, only included in the docker config when set
+	// +optional
+	Email string `json:"email,omitempty"`
+	// HtpasswdKey is the key to store an htpasswd file with a bcrypt hash of the password in, to configure the
+	// authentication of the registry itself
+	// +optional
+	HtpasswdKey string `json:"htpasswdKey,omitempty"`
+}
+
+// HtpasswdValueSpec generates a password for each user, and an htpasswd file with the bcrypt hashes of the passwords.
+// The hash of a user is kept as long as it matches the password, so the file only changes when the users do.
+type HtpasswdValueSpec struct {
+	// Users of the htpasswd file
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	Users []string `json:"users"`
+	// Password configures how the password of each user is generated, defaults to a length of 32
+	// +optional
+	Password *GeneratedValueSpec `json:"password,omitempty"`
+	// PasswordKeyPrefix is prepended to the username to form the key holding the password of the user.
+	// Defaults to the key of the htpasswd file followed by a dot, e.g. auth.admin
+	// +optional
+	PasswordKeyPrefix string `json:"passwordKeyPrefix,omitempty"`
+	// Cost of the bcrypt hashes, defaults to 10
+	// +kubebuilder:validation:Minimum=4
+	// +kubebuilder:validation:Maximum=16
+	// +optional
+	Cost int `json:"cost,omitempty"`
+}
+
+// GetPasswordKey returns the key holding the password of the user, for the htpasswd file stored in the given key
+func (s *HtpasswdValueSpec) GetPasswordKey(key, user string) string {
+	if s.PasswordKeyPrefix != "" {
+		return s.PasswordKeyPrefix + user
+	}
+	return key + "." + user
+}
+
+// HashAlgorithm is the algorithm of a hashed value
+type HashAlgorithm string
+
+const (
+	// HashAlgorithmBcrypt hashes the value with bcrypt
+	HashAlgorithmBcrypt HashAlgorithm = "bcrypt"
+	// HashAlgorithmArgon2id hashes the value with argon2id
+	HashAlgorithmArgon2id HashAlgorithm = "argon2id"
+	// HashAlgorithmScrypt hashes the value with scrypt
+	HashAlgorithmScrypt HashAlgorithm = "scrypt"
+	// HashAlgorithmScramSHA256 derives a SCRAM-SHA-256 verifier from the value, as used by PostgreSQL
+	HashAlgorithmScramSHA256 HashAlgorithm = "scram-sha-256"
+)
+
+// HashedValueSpec derives a password hash from another key of the same secret. The hash is kept as long as it matches
+// the value of the key and the parameters, and only replaced when either changes.
+// +kubebuilder:validation:XValidation:rule="!has(self.cost) || self.cost <= 16 || (has(self.algorithm) && self.algorithm != 'bcrypt')",message="the bcrypt cost must be at most 16"
+// +kubebuilder:validation:XValidation:rule="!has(self.iterations) || self.iterations <= 16 || !has(self.algorithm) || self.algorithm != 'argon2id'",message="the argon2id iterations must be at most 16"
+// +kubebuilder:validation:XValidation:rule="!has(self.memory) || self.memory <= 65536 || !has(self.algorithm) || self.algorithm != 'argon2id'",message="the argon2id memory must be at most 65536 KiB"
+// +kubebuilder:validation:XValidation:rule="!has(self.parallelism) || self.parallelism <= 16 || !has(self.algorithm) || self.algorithm != 'scrypt'",message="the scrypt parallelism must be at most 16"
+type HashedValueSpec struct {
+	// Key of the value in the same secret that is hashed
+	Key string `json:"key"`
+	// Algorithm of the hash. argon2id and scrypt hashes use the PHC string format, scram-sha-256 hashes use the format
+	// PostgreSQL stores passwords in.
+	// +kubebuilder:validation:Enum=bcrypt;argon2id;scrypt;scram-sha-256
+	// +kubebuilder:default=bcrypt
+	// +optional
+	Algorithm HashAlgorithm `json:"algorithm,omitempty"`
+	// Cost is the bcrypt cost, defaults to 10 and at most 16, or the base 2 logarithm of the scrypt CPU/memory cost,
+	// defaults to 15 and at most 16
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16
+	// +optional
+	Cost int `json:"cost,omitempty"`
+	// Iterations is the number of argon2id passes, defaults to 3 and at most 16, or the number of scram-sha-256
+	// iterations, defaults to 4096
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000000
+	// +optional
+	Iterations int `json:"iterations,omitempty"`
+	// Memory is the argon2id memory in KiB, defaults to and at most 65536 (64 MiB)
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	Memory int `json:"memory,omitempty"`
+	// Parallelism is the number of argon2id threads, defaults to 4, or the scrypt parallelization parameter, defaults
+	// to 1 and at most 16
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	Parallelism int `json:"parallelism,omitempty"`
 }
 
 type SshKeyValueSpec struct {
@@ -133,11 +333,46 @@ type StaticValueSpec struct {
 
 type TemplatedValueSpec struct {
 	// Template is a string that will be templated using the key-value pairs in the secret. This is a go template string.
+	// See the README for the available values and functions.
 	Template string `json:"template"`
 	// Input Secret reference is a reference to a secret that will be used to template the value
 	// The value will be templated using the key-value pairs in the secret
 	// +optional
 	InputSecretRef *SecretReference `json:"inputSecretRef,omitempty"`
+	// Inputs are named input secrets or config maps, available in the template as .Inputs.<alias>.<key>
+	// Use index for aliases that are not valid identifiers, e.g. {{ index .Inputs "db-creds" "password" }}
+	// +optional
+	Inputs map[string]TemplateInput `json:"inputs,omitempty"`
+	// MissingKeyPolicy controls what happens when the template refers to a key that does not exist.
+	// Error fails rendering, Zero renders an empty string and Default renders "<no value>".
+	// Use index to look up optional keys with the Error policy, e.g. {{ index .Ref "port" | default "5432" }}
+	// When not set, missing keys render as "<no value>". With webhooks enabled, new GeneratedSecrets default to Error.
+	// +kubebuilder:validation:Enum=Error;Zero;Default
+	// +optional
+	MissingKeyPolicy MissingKeyPolicy `json:"missingKeyPolicy,omitempty"`
+}
+
+// MissingKeyPolicy controls how a template handles references to keys that do not exist
+type MissingKeyPolicy string
+
+const (
+	// MissingKeyError fails rendering when the template refers to a key that does not exist
+	MissingKeyError MissingKeyPolicy = "Error"
+	// MissingKeyZero renders keys that do not exist as an empty string
+	MissingKeyZero MissingKeyPolicy = "Zero"
+	// MissingKeyDefault renders keys that do not exist as "<no value>"
+	MissingKeyDefault MissingKeyPolicy = "Default"
+)
+
+// TemplateInput is a named source of key-value pairs for a templated value
+// +kubebuilder:validation:XValidation:rule="has(self.secretRef) != has(self.configMapRef)",message="exactly one of secretRef or configMapRef must be set"
+type TemplateInput struct {
+	// Secret reference is a reference to the secret holding the input values
+	// +optional
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+	// ConfigMap reference is a reference to the config map holding the input values, both data and binaryData are available
+	// +optional
+	ConfigMapRef *ConfigMapReference `json:"configMapRef,omitempty"`
 }
 
 // SecretReference represents a reference to a Secret in a specific namespace
@@ -149,6 +384,15 @@ type SecretReference struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// ConfigMapReference represents a reference to a ConfigMap in a specific namespace
+type ConfigMapReference struct {
+	// Name of the config map
+	Name string `json:"name"`
+	// Namespace of the config map. If empty, defaults to the namespace of the GeneratedSecret
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
 type GeneratedValueSpec struct {
 	// Lengt of the  generated value
 	// +optional
@@ -170,6 +414,84 @@ type GeneratedValueSpec struct {
 
 	// +optional
 	NoRepeat bool `json:"noRepeatedValues"`
+
+	// MinEntropyBits derives the length of generated values from the characters they are made of, so every value has
+	// at least this many bits of entropy. Length, MinLength and MaxLength do not apply when it is set.
+	// +kubebuilder:validation:Maximum=4096
+	// +optional
+	MinEntropyBits uint32 `json:"minEntropyBits,omitempty"`
+
+	// MinLower is the minimum number of lower case letters of generated values
+	// +optional
+	MinLower uint32 `json:"minLower,omitempty"`
+
+	// MinUpper is the minimum number of upper case letters of generated values
+	// +optional
+	MinUpper uint32 `json:"minUpper,omitempty"`
+
+	// MinDigits is the minimum number of digits of generated values, MaxDigits is raised to it when lower
+	// +optional
+	MinDigits uint32 `json:"minDigits,omitempty"`
+
+	// MinSymbols is the minimum number of symbols of generated values, MaxSymbols is raised to it when lower
+	// +optional
+	MinSymbols uint32 `json:"minSymbols,omitempty"`
+
+	// Symbols replaces the symbols generated values may contain, e.g. to leave out characters that need escaping in
+	// connection URLs. Defaults to ~!@#$%^&*()_+`-={}|[]\:"<>?,./
+	// +optional
+	Symbols string `json:"symbols,omitempty"`
+
+	// Alphabet is the full set of characters generated values are made of. MaxDigits, MaxSymbols, NoUpper and Symbols
+	// do not apply when it is set, the minimum number of characters of each class does.
+	// +optional
+	Alphabet string `json:"alphabet,omitempty"`
+
+	// ExcludeAmbiguous leaves out characters that are easily mistaken for one another: 0, O, 1, l, I and |
+	// +optional
+	ExcludeAmbiguous bool `json:"excludeAmbiguous,omitempty"`
+}
+
+// PassphraseValueSpec generates a diceware passphrase of words from the EFF large wordlist, which is embedded in the
+// operator. Every word adds about 12.9 bits of entropy.
+type PassphraseValueSpec struct {
+	// Words is the number of words of the passphrase
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +kubebuilder:default=6
+	// +optional
+	Words int `json:"words,omitempty"`
+
+	// Separator is placed between the words. Defaults to -, an empty separator is best combined with capitalization
+	// so the words can be told apart.
+	// +optional
+	Separator *string `json:"separator,omitempty"`
+
+	// Capitalize capitalizes the first letter of every word
+	// +optional
+	Capitalize bool `json:"capitalize,omitempty"`
+
+	// Digit appends a random digit to one of the words, for systems that require a digit
+	// +optional
+	Digit bool `json:"digit,omitempty"`
+}
+
+// GetSeparator returns the separator of the words
+func (spec *PassphraseValueSpec) GetSeparator() string {
+	if spec.Separator == nil {
+		return "-"
+	}
+	return *spec.Separator
+}
+
+// PatternValueSpec generates a random value matching a regular expression, e.g. svc-[a-z]{6} or AKIA[A-Z0-9]{16}.
+// Only literals, character classes, ., groups, alternations, ? and bounded repetitions {n} and {n,m} are supported.
+// Character classes and . generate printable ASCII characters only.
+type PatternValueSpec struct {
+	// Expression is the regular expression values are generated from. Anchors are allowed and ignored
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=1024
+	Expression string `json:"expression"`
 }
 
 // GeneratedSecretStatus defines the observed state of Secret.
@@ -194,6 +516,94 @@ type GeneratedSecretStatus struct {
 	// SecretsCount is the total number of secrets that have been generated
 	// +optional
 	SecretsCount int `json:"secretsCount,omitempty"`
+
+	// Targets describes the observed state of the secret in each target namespace
+	// +optional
+	// +listType=map
+	// +listMapKey=namespace
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// StaleSecrets lists the secrets generated for targets that are no longer part of the spec. They are left in
+	// place, are not deleted along with the GeneratedSecret, and are linked again when their target is added back.
+	// +optional
+	StaleSecrets []SecretReference `json:"staleSecrets,omitempty"`
+
+	// ReadyTargets is the number of target secrets that are in sync
+	// +optional
+	ReadyTargets int `json:"readyTargets,omitempty"`
+
+	// FailedTargets is the number of target secrets that failed to sync
+	// +optional
+	FailedTargets int `json:"failedTargets,omitempty"`
+
+	// Templates records the inputs each templated key was rendered from. A templated key is rendered again
+	// when the resource version of one of its inputs changes.
+	// +optional
+	// +listType=map
+	// +listMapKey=key
+	Templates []TemplateStatus `json:"templates,omitempty"`
+
+	// Entropy records the entropy of each generated key, as guaranteed by the spec its value was generated with, so
+	// audits can confirm that generated values meet a policy without reading them. Keys overridden in a target are
+	// recorded as <namespace>/<key>.
+	// +optional
+	// +listType=map
+	// +listMapKey=key
+	Entropy []EntropyStatus `json:"entropy,omitempty"`
+}
+
+// EntropyStatus records the entropy of a generated key
+type EntropyStatus struct {
+	// Key of the generated value in the secret data
+	Key string `json:"key"`
+	// Bits of entropy the value has at least
+	Bits int32 `json:"bits"`
+}
+
+// TemplateStatus records the inputs a templated key was rendered from
+type TemplateStatus struct {
+	// Key of the templated value in the secret data
+	Key string `json:"key"`
+	// Inputs are the versions of the secrets and config maps the value was rendered from
+	// +optional
+	Inputs []TemplateInputVersion `json:"inputs,omitempty"`
+}
+
+// TemplateInputVersion identifies the version of a template input
+type TemplateInputVersion struct {
+	// Kind of the input, either Secret or ConfigMap
+	Kind string `json:"kind"`
+	// Namespace of the input
+	Namespace string `json:"namespace"`
+	// Name of the input
+	Name string `json:"name"`
+	// ResourceVersion of the input at the time of rendering
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+type TargetState string
+
+const (
+	// TargetStateReady indicates that the secret in the target namespace is in sync with the spec
+	TargetStateReady TargetState = "Ready"
+	// TargetStateFailed indicates that the secret in the target namespace could not be synced
+	TargetStateFailed TargetState = "Failed"
+)
+
+// TargetStatus describes the observed state of the generated secret in a single target namespace
+type TargetStatus struct {
+	// Namespace of the target secret
+	Namespace string `json:"namespace"`
+	// Name of the target secret
+	Name string `json:"name"`
+	// State of the target secret
+	State TargetState `json:"state"`
+	// LastError holds the error of the last failed sync attempt, if any
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// LastSyncTime is the last time the target secret was created, updated or changed state
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
 }
 
 // GeneratedSecretsRef is a list of references to secrets
@@ -233,6 +643,85 @@ func (s *GeneratedSecret) GetSecretName() string {
 	return s.GetName()
 }
 
+// GetTargetNamespaces returns the sorted namespaces of all targets, both from the metadata and from the target overrides
+func (s *GeneratedSecret) GetTargetNamespaces() []string {
+	seen := map[string]bool{}
+	namespaces := []string{}
+	for _, namespace := range s.Spec.Metadata.GetNamespaces() {
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	for _, target := range s.Spec.Targets {
+		if !seen[target.Namespace] {
+			seen[target.Namespace] = true
+			namespaces = append(namespaces, target.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// GetTarget returns the overrides of the target in the given namespace, or nil when the target has no overrides
+func (s *GeneratedSecret) GetTarget(namespace string) *TargetSpec {
+	for i := range s.Spec.Targets {
+		if s.Spec.Targets[i].Namespace == namespace {
+			return &s.Spec.Targets[i]
+		}
+	}
+	return nil
+}
+
+// GetTargetSecretName returns the name of the generated secret in the given namespace
+func (s *GeneratedSecret) GetTargetSecretName(namespace string) string {
+	if target := s.GetTarget(namespace); target != nil && target.Name != "" {
+		return target.Name
+	}
+	return s.GetSecretName()
+}
+
+// GetTargetSecretLabels returns the labels to be added to the generated secret in the given namespace
+func (s *GeneratedSecret) GetTargetSecretLabels(namespace string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range s.GetSecretLabels() {
+		labels[k] = v
+	}
+	if target := s.GetTarget(namespace); target != nil {
+		for k, v := range target.Labels {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// GetTargetSecretAnnotations returns the annotations to be added to the generated secret in the given namespace
+func (s *GeneratedSecret) GetTargetSecretAnnotations(namespace string) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range s.GetSecretAnnotations() {
+		annotations[k] = v
+	}
+	if target := s.GetTarget(namespace); target != nil {
+		for k, v := range target.Annotations {
+			annotations[k] = v
+		}
+	}
+	return annotations
+}
+
+// GetTargetOverrides returns the template items that are overridden in the given namespace
+func (s *GeneratedSecret) GetTargetOverrides(namespace string) SecretValueItems {
+	if target := s.GetTarget(namespace); target != nil {
+		return target.Data
+	}
+	return nil
+}
+
+// TracksTargetsInObjects returns true if the target states are tracked in GeneratedSecretTarget objects
+func (s *GeneratedSecret) TracksTargetsInObjects() bool {
+	return s.Spec.TargetTracking == TrackTargetsInObjects
+}
+
 // GetSecretLabels returns a hashmap of the labels to be added to the generated secret
 func (s *GeneratedSecret) GetSecretLabels() map[string]string {
 	return s.Spec.Metadata.GetLabels()
@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the defaulting webhook of GeneratedSecrets with the manager
+func (s *GeneratedSecret) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(s).
+		WithDefaulter(&generatedSecretDefaulter{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-apps-k8s-containerinfra-com-v1-generatedsecret,mutating=true,failurePolicy=fail,sideEffects=None,groups=apps.k8s.containerinfra.com,resources=generatedsecrets,verbs=create,versions=v1,name=mgeneratedsecret.kb.io,admissionReviewVersions=v1
+
+// generatedSecretDefaulter sets the defaults of GeneratedSecrets when they are created
+// +kubebuilder:object:generate=false
+type generatedSecretDefaulter struct{}
+
+// Default sets the defaults of a new GeneratedSecret. Existing GeneratedSecrets are never defaulted, so fields that
+// were added later keep their legacy behaviour when they are not set.
+func (d *generatedSecretDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	generatedSecret, ok := obj.(*GeneratedSecret)
+	if !ok {
+		return fmt.Errorf("expected a GeneratedSecret but got %T", obj)
+	}
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation != admissionv1.Create {
+		return nil
+	}
+	generatedSecret.defaultMissingKeyPolicies()
+	return nil
+}
+
+// defaultMissingKeyPolicies sets the Error missing key policy on the templates that do not set a policy
+func (s *GeneratedSecret) defaultMissingKeyPolicies() {
+	setPolicy := func(spec *TemplatedValueSpec) {
+		if spec != nil && spec.MissingKeyPolicy == "" {
+			spec.MissingKeyPolicy = MissingKeyError
+		}
+	}
+	items := []SecretValueItems{s.Spec.Template.Data}
+	for _, target := range s.Spec.Targets {
+		items = append(items, target.Data)
+	}
+	for _, data := range items {
+		for _, item := range data {
+			setPolicy(item.Templated)
+		}
+	}
+	if document := s.Spec.Template.Document; document != nil {
+		setPolicy(&document.TemplatedValueSpec)
+	}
+}
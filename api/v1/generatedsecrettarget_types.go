@@ -0,0 +1,60 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GeneratedSecretTargetSpec identifies the target secret that is tracked by a GeneratedSecretTarget
+type GeneratedSecretTargetSpec struct {
+	// GeneratedSecretName is the name of the GeneratedSecret this target belongs to
+	GeneratedSecretName string `json:"generatedSecretName"`
+	// Namespace of the target secret
+	Namespace string `json:"namespace"`
+	// Name of the target secret
+	Name string `json:"name"`
+}
+
+// GeneratedSecretTargetStatus defines the observed state of a single target secret
+type GeneratedSecretTargetStatus struct {
+	// SecretRef references the generated secret once it has been created
+	// +optional
+	SecretRef *GeneratedSecretRef `json:"secretRef,omitempty"`
+	// State of the target secret
+	// +optional
+	State TargetState `json:"state,omitempty"`
+	// LastError holds the error of the last failed sync attempt, if any
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+	// LastSyncTime is the last time the target secret was created, updated or changed state
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Target Namespace",type=string,JSONPath=`.spec.namespace`
+//+kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.name`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// GeneratedSecretTarget tracks a single target secret of a GeneratedSecret that uses the Objects target tracking mode.
+// It is owned and maintained by the operator and lives in the namespace of its GeneratedSecret.
+type GeneratedSecretTarget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GeneratedSecretTargetSpec   `json:"spec,omitempty"`
+	Status GeneratedSecretTargetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GeneratedSecretTargetList contains a list of GeneratedSecretTargets
+type GeneratedSecretTargetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GeneratedSecretTarget `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GeneratedSecretTarget{}, &GeneratedSecretTargetList{})
+}
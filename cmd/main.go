@@ -18,23 +18,34 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	apiv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
 	"github.com/containerinfra/kube-secrets-operator/controllers/generatedsecret"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/templated"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -55,11 +66,38 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var leaderElectionID string
+	var maxConcurrentReconciles int
+	var maxConcurrentWrites int
+	var rateLimiterBaseDelay time.Duration
+	var rateLimiterMaxDelay time.Duration
+	var rateLimiterQPS float64
+	var rateLimiterBurst int
+	var templateTimeout time.Duration
+	var templateMaxSize int
+	var watchTemplateInputs bool
+	var templateInputNamespaces string
+	var templateInputSelector string
+	var enableWebhooks bool
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":7712", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":7713", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&leaderElectionID, "leader-election-id", "kube-secrets-operator.k8s.containerinfra.com", "The ID to use for leader election.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1, "The maximum number of GeneratedSecrets that are reconciled in parallel. "+
+		"Every reconcile may take up to 64 MiB of memory to hash an argon2id or scrypt value, which the memory limit of the operator must allow for.")
+	flag.IntVar(&maxConcurrentWrites, "max-concurrent-secret-writes", generatedsecret.DefaultMaxConcurrentWrites, "The maximum number of target secrets of a single GeneratedSecret that are written in parallel.")
+	flag.DurationVar(&rateLimiterBaseDelay, "rate-limiter-base-delay", 5*time.Millisecond, "The initial delay before a failed GeneratedSecret is retried, doubled on every failure.")
+	flag.DurationVar(&rateLimiterMaxDelay, "rate-limiter-max-delay", 1000*time.Second, "The maximum delay before a failed GeneratedSecret is retried.")
+	flag.Float64Var(&rateLimiterQPS, "rate-limiter-qps", 10, "The overall number of GeneratedSecrets that may be requeued per second.")
+	flag.IntVar(&rateLimiterBurst, "rate-limiter-burst", 100, "The overall burst of GeneratedSecrets that may be requeued at once.")
+	flag.DurationVar(&templateTimeout, "template-timeout", templated.DefaultTimeout, "The maximum time a single template may take to execute.")
+	flag.IntVar(&templateMaxSize, "template-max-output-size", templated.DefaultMaxSize, "The maximum size in bytes of the output of a single template, at most the size limit of a secret.")
+	flag.BoolVar(&watchTemplateInputs, "watch-template-inputs", true, "Watch the metadata of secrets and config maps to render templated values again when one of their inputs changes. "+
+		"Without a namespace or label selector all secrets and config maps in the cluster are watched.")
+	flag.StringVar(&templateInputNamespaces, "template-input-namespaces", "", "Comma separated list of namespaces whose secrets and config maps are watched as template inputs, all namespaces when empty.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", os.Getenv("ENABLE_WEBHOOKS") == "true", "Serve the admission webhooks, which default new GeneratedSecrets. "+
+		"Requires a serving certificate in /tmp/k8s-webhook-server/serving-certs, defaults to the ENABLE_WEBHOOKS environment variable.")
+	flag.StringVar(&templateInputSelector, "template-input-label-selector", "", "Label selector that secrets and config maps must match to be watched as template inputs, e.g. app.kubernetes.io/part-of=my-app.")
 
 	opts := zap.Options{
 		Development: true,
@@ -69,8 +107,29 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if templateMaxSize <= 0 || templateMaxSize > corev1.MaxSecretSize {
+		setupLog.Error(fmt.Errorf("must be between 1 and %d bytes", corev1.MaxSecretSize), "invalid template-max-output-size", "size", templateMaxSize)
+		os.Exit(1)
+	}
+	if templateTimeout <= 0 {
+		setupLog.Error(fmt.Errorf("must be positive"), "invalid template-timeout", "timeout", templateTimeout)
+		os.Exit(1)
+	}
+	inputCacheOptions, err := templateInputCacheOptions(templateInputNamespaces, templateInputSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid template-input-label-selector", "selector", templateInputSelector)
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache: cache.Options{
+			// Only cache the secrets managed by the operator, other secrets are read directly from the API server
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {Label: generatedsecret.ManagedSecretSelector()},
+			},
+			DefaultTransform: cache.TransformStripManagedFields(),
+		},
 		Metrics: metricsserver.Options{
 			BindAddress: metricsAddr,
 		},
@@ -84,15 +143,52 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Template inputs are watched through a separate, metadata only cache, as most of them are not managed by the operator.
+	// Inputs may live in any namespace, so the cache is only scoped when namespaces or a label selector are configured.
+	var inputCache cache.Cache
+	if watchTemplateInputs {
+		inputCacheOptions.HTTPClient = mgr.GetHTTPClient()
+		inputCacheOptions.Scheme = mgr.GetScheme()
+		inputCacheOptions.Mapper = mgr.GetRESTMapper()
+		inputCache, err = cache.New(mgr.GetConfig(), inputCacheOptions)
+		if err != nil {
+			setupLog.Error(err, "unable to create template input cache")
+			os.Exit(1)
+		}
+		if err := mgr.Add(inputCache); err != nil {
+			setupLog.Error(err, "unable to add template input cache")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&generatedsecret.GeneratedSecretReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("generated-secret-controller"),
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		Recorder:   mgr.GetEventRecorderFor("generated-secret-controller"),
+		APIReader:  mgr.GetAPIReader(),
+		InputCache: inputCache,
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		MaxConcurrentWrites:     maxConcurrentWrites,
+		RateLimiter: workqueue.NewTypedMaxOfRateLimiter(
+			workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](rateLimiterBaseDelay, rateLimiterMaxDelay),
+			&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(rateLimiterQPS), rateLimiterBurst)},
+		),
+		TemplateLimits: templated.Limits{
+			Timeout: templateTimeout,
+			MaxSize: templateMaxSize,
+		},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GeneratedSecret")
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		if err = (&apiv1.GeneratedSecret{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GeneratedSecret")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -110,3 +206,28 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// templateInputCacheOptions returns the options of the cache that watches template inputs, limited to the given comma
+// separated namespaces and label selector when they are set
+func templateInputCacheOptions(namespaces, selector string) (cache.Options, error) {
+	opts := cache.Options{
+		DefaultTransform: generatedsecret.TransformInputMetadata,
+	}
+	for _, namespace := range strings.Split(namespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace == "" {
+			continue
+		}
+		if opts.DefaultNamespaces == nil {
+			opts.DefaultNamespaces = map[string]cache.Config{}
+		}
+		opts.DefaultNamespaces[namespace] = cache.Config{}
+	}
+	if selector != "" {
+		parsed, err := labels.Parse(selector)
+		if err != nil {
+			return cache.Options{}, err
+		}
+		opts.DefaultLabelSelector = parsed
+	}
+	return opts, nil
+}
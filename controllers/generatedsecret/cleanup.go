@@ -5,13 +5,15 @@ import (
 
 	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // cleanup deletes the secrets that were generated by the GeneratedSecret if the DeletionPolicy is set to DeleteOnCleanup
-// This function is called when the GeneratedSecret is deleted
+// This function is called when the GeneratedSecret is deleted. Stale secrets, whose target is no longer part of the
+// spec, are left in place.
 func (r *GeneratedSecretReconciler) cleanup(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret) error {
 	logger := log.FromContext(ctx)
 
@@ -19,23 +21,21 @@ func (r *GeneratedSecretReconciler) cleanup(ctx context.Context, generatedSecret
 		return nil
 	}
 
-	for _, secretRef := range generatedSecret.Status.SecretsGeneratedRef.Secrets {
-		secret := &corev1.Secret{}
-		err := r.Client.Get(ctx, types.NamespacedName{
-			Name:      secretRef.Name,
-			Namespace: secretRef.Namespace,
-		}, secret)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				continue
-			}
-			logger.Error(err, "failed to get secret for cleanup")
-			continue
-		}
+	managedSecrets, err := r.listManagedSecrets(ctx, *generatedSecret)
+	if err != nil {
+		return err
+	}
+	wanted := wantedSecrets(*generatedSecret)
+	for i := range managedSecrets {
+		secret := &managedSecrets[i]
 		if !isSecretOwnedBy(*generatedSecret, *secret) {
 			logger.Info("secret not owned by GeneratedSecret, skipping deletion", "secret", secret.Name)
 			continue
 		}
+		if !wanted[types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}] {
+			logger.Info("secret is stale, leaving it in place", "secret", secret.Name, "namespace", secret.Namespace)
+			continue
+		}
 		err = r.Client.Delete(ctx, secret)
 		if err != nil {
 			if !errors.IsNotFound(err) {
@@ -47,3 +47,17 @@ func (r *GeneratedSecretReconciler) cleanup(ctx context.Context, generatedSecret
 	}
 	return nil
 }
+
+// reportStaleSecrets records the stale secrets in the status, with an event for every secret that became stale.
+// Stale secrets are left in place, they are linked again when their target is added back to the spec.
+func (r *GeneratedSecretReconciler) reportStaleSecrets(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret, stale []corev1.Secret) error {
+	previous := generatedSecret.Status.StaleSecrets
+	for _, ref := range setStaleSecrets(&generatedSecret.Status, stale) {
+		log.FromContext(ctx).Info("secret is stale, leaving it in place", "secret", ref.Name, "namespace", ref.Namespace)
+		r.Recorder.Eventf(generatedSecret, corev1.EventTypeNormal, "Stale secret", "The secret %s/%s no longer belongs to a target and is left in place", ref.Namespace, ref.Name)
+	}
+	if equality.Semantic.DeepEqual(previous, generatedSecret.Status.StaleSecrets) {
+		return nil
+	}
+	return r.updateStatusOrRetry(ctx, generatedSecret)
+}
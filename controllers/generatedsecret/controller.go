@@ -22,17 +22,26 @@ import (
 	"time"
 
 	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/pwdgen"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/templated"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
@@ -48,12 +57,32 @@ type GeneratedSecretReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// APIReader reads directly from the API server. The cache only holds operator managed secrets,
+	// so secrets that are not managed by the operator, such as template inputs, are read through this reader.
+	APIReader client.Reader
+
+	// InputCache is used to watch the secrets and config maps that templates read from, so templated values are
+	// rendered again when an input changes. Inputs are not watched when no cache is set.
+	InputCache cache.Cache
+
+	// MaxConcurrentReconciles is the maximum number of GeneratedSecrets that are reconciled in parallel
+	MaxConcurrentReconciles int
+	// MaxConcurrentWrites is the maximum number of target secrets of a single GeneratedSecret that are written in parallel
+	MaxConcurrentWrites int
+	// RateLimiter limits how frequently GeneratedSecrets are requeued. Defaults to the controller-runtime rate limiter
+	RateLimiter workqueue.TypedRateLimiter[reconcile.Request]
+	// TemplateLimits bounds the execution time and output size of templates. Defaults to the limits of the templated package
+	TemplateLimits templated.Limits
 }
 
 //+kubebuilder:rbac:groups=apps.k8s.containerinfra.com,resources=generatedsecrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps.k8s.containerinfra.com,resources=generatedsecrets/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=apps.k8s.containerinfra.com,resources=generatedsecrets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=apps.k8s.containerinfra.com,resources=generatedsecrettargets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *GeneratedSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -61,6 +90,9 @@ func (r *GeneratedSecretReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	if err := r.Get(ctx, req.NamespacedName, &generatedSecret); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	if err := r.loadTargets(ctx, &generatedSecret); err != nil {
+		return ctrl.Result{}, err
+	}
 
 	if !generatedSecret.ObjectMeta.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(&generatedSecret, finalizerName) {
@@ -95,9 +127,12 @@ func (r *GeneratedSecretReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GeneratedSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&generatedsecretv1.GeneratedSecret{}).
-		WithEventFilter(predicate.Funcs{
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &generatedsecretv1.GeneratedSecret{}, templateInputIndexKey, indexTemplateInputs); err != nil {
+		return fmt.Errorf("failed to index GeneratedSecrets by template inputs: %w", err)
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&generatedsecretv1.GeneratedSecret{}, builder.WithPredicates(predicate.Funcs{
 			CreateFunc: func(e event.CreateEvent) bool {
 				return true
 			},
@@ -112,12 +147,42 @@ func (r *GeneratedSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			GenericFunc: func(e event.GenericEvent) bool {
 				return false
 			},
-		}).
-		Complete(r)
+		})).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.RateLimiter,
+		})
+
+	if r.InputCache != nil {
+		for _, kind := range []string{pwdgen.InputKindSecret, pwdgen.InputKindConfigMap} {
+			b = b.WatchesRawSource(source.Kind(r.InputCache, inputMetadata(kind), handler.TypedEnqueueRequestsFromMapFunc(r.requestsForInput(kind)), inputChangedPredicate))
+		}
+	}
+	return b.Complete(r)
+}
+
+// uncachedReader returns the reader to use for objects that are not held by the cache
+func (r *GeneratedSecretReconciler) uncachedReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
 }
 
 func (r *GeneratedSecretReconciler) updateStatusOrRetry(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret) error {
-	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+	summarizeTargets(&generatedSecret.Status)
+	if err := r.syncTargetObjects(ctx, generatedSecret); err != nil {
+		return err
+	}
+
+	status := generatedSecret.Status.DeepCopy()
+	if generatedSecret.TracksTargetsInObjects() {
+		// The per-target state lives in the GeneratedSecretTarget objects, only keep the aggregates
+		status.SecretsGeneratedRef.Secrets = []generatedsecretv1.GeneratedSecretRef{}
+		status.Targets = nil
+	}
+
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
 		return true
 	}, func() error {
 		// Fetch the latest version of the GeneratedSecret object
@@ -127,7 +192,7 @@ func (r *GeneratedSecretReconciler) updateStatusOrRetry(ctx context.Context, gen
 		}
 
 		// Update the status of the latest object
-		latest.Status = generatedSecret.Status
+		latest.Status = *status
 		if err := r.Client.Status().Update(ctx, latest); err != nil {
 			return fmt.Errorf("failed to update GeneratedSecret status: %w", err)
 		}
@@ -138,4 +203,8 @@ func (r *GeneratedSecretReconciler) updateStatusOrRetry(ctx context.Context, gen
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	return r.loadTargets(ctx, generatedSecret)
 }
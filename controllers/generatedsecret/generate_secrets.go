@@ -3,112 +3,144 @@ package generatedsecret
 import (
 	"context"
 	"fmt"
-	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
 	"github.com/containerinfra/kube-secrets-operator/pkg/createsecret"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/pwdgen"
 	"github.com/containerinfra/kube-secrets-operator/pkg/utils"
 )
 
 // createMissingPasswordSecrets will create missing password secrets in the cluster
-func (r *GeneratedSecretReconciler) createMissingPasswordSecrets(ctx context.Context, generatedSecret generatedsecretv1.GeneratedSecret, validSecrets []corev1.Secret) error {
+// The shared data holds the values shared by all targets, as held by the valid secrets
+func (r *GeneratedSecretReconciler) createMissingPasswordSecrets(ctx context.Context, generatedSecret generatedsecretv1.GeneratedSecret, validSecrets []corev1.Secret, sharedData map[string][]byte) error {
 	logger := log.FromContext(ctx)
 
 	if len(validSecrets) == 0 {
 		return fmt.Errorf("invalid state: no valid secrets")
 	}
 
-	// copy the first secret over to all other namespaces
-	validSecret := validSecrets[0]
-	secretData := validSecret.Data
+	// copy the shared values over to all other namespaces
+	secrets := generatePasswordSecrets(generatedSecret, sharedData)
 
-	// Now fetch the data and sync it to the new secrets
-	secrets := generatePasswordSecrets(generatedSecret, secretData)
-
-	initalLength := len(generatedSecret.Status.SecretsGeneratedRef.Secrets)
+	initalStatus := generatedSecret.Status.DeepCopy()
 
 	// clear it, rebuild this list
 	generatedSecret.Status.SecretsGeneratedRef.Secrets = []generatedsecretv1.GeneratedSecretRef{}
 
+	// Create the secrets for all targets without a valid secret in parallel
+	validByKey := indexSecrets(validSecrets)
+	missingSecrets := []corev1.Secret{}
+	missingIndex := map[types.NamespacedName]int{}
+	for _, secret := range secrets {
+		if _, valid := validByKey[types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}]; !valid {
+			missingIndex[types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}] = len(missingSecrets)
+			missingSecrets = append(missingSecrets, secret)
+		}
+	}
+	// The values that are specific to a target are rendered for each new target
+	// Secrets of this GeneratedSecret that already exist, e.g. because they were modified externally or their namespace
+	// was removed from the spec and added again, are linked instead
+	renderErrs := r.renderTargetValues(ctx, &generatedSecret, pwdgen.NewCachingFetcher(r.uncachedReader()), missingSecrets, sharedData, r.newRenderContext(&generatedSecret), pwdgen.RenderedInputs{})
+	index := secretIndex(missingSecrets)
+	linked := make([]bool, len(missingSecrets))
+	errs := r.writeSecrets(ctx, missingSecrets, skipFailed(missingSecrets, renderErrs, func(ctx context.Context, secret *corev1.Secret) error {
+		var err error
+		linked[index[secret]], err = r.createOrLinkSecret(ctx, generatedSecret, secret)
+		return err
+	}))
+
 	for _, secret := range secrets {
-		if secretIsListedInValidSecrets(validSecrets, secret) {
-			generatedSecret.Status.SecretsGeneratedRef.Secrets = append(generatedSecret.Status.SecretsGeneratedRef.Secrets, utils.GetGeneratedSecretRef(secret))
+		i, missing := missingIndex[types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}]
+		if !missing {
+			validSecret := validByKey[types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}]
+			generatedSecret.Status.SecretsGeneratedRef.Secrets = append(generatedSecret.Status.SecretsGeneratedRef.Secrets, utils.GetGeneratedSecretRef(*validSecret))
+			setTargetReady(&generatedSecret.Status, secret.GetNamespace(), secret.GetName(), false)
 			continue
 		}
 
-		err := r.Client.Create(ctx, &secret)
-		if err != nil {
-			if !errors.IsAlreadyExists(err) {
-				logger.Info(fmt.Sprintf("Failed to create secret: %v, possibily a secret modified externally", err))
-			}
+		createdSecret := missingSecrets[i]
+		if errs[i] != nil {
+			setTargetFailed(&generatedSecret.Status, createdSecret.GetNamespace(), createdSecret.GetName(), errs[i])
 			continue
 		}
-		r.Recorder.Eventf(&generatedSecret, corev1.EventTypeNormal, "Created secret", "Created a new secret %s/%s", secret.GetNamespace(), secret.GetName())
+		if linked[i] {
+			logger.Info("linked existing secret", "namespace", createdSecret.GetNamespace(), "name", createdSecret.GetName())
+			r.Recorder.Eventf(&generatedSecret, corev1.EventTypeNormal, "Linked secret", "Linked the existing secret %s/%s", createdSecret.GetNamespace(), createdSecret.GetName())
+		} else {
+			r.Recorder.Eventf(&generatedSecret, corev1.EventTypeNormal, "Created secret", "Created a new secret %s/%s", createdSecret.GetNamespace(), createdSecret.GetName())
+		}
 
 		// New secret, so simply add it to the secrets ref
-		generatedSecret.Status.SecretsGeneratedRef.Secrets = append(generatedSecret.Status.SecretsGeneratedRef.Secrets, utils.GetGeneratedSecretRef(secret))
+		generatedSecret.Status.SecretsGeneratedRef.Secrets = append(generatedSecret.Status.SecretsGeneratedRef.Secrets, utils.GetGeneratedSecretRef(createdSecret))
+		setTargetReady(&generatedSecret.Status, createdSecret.GetNamespace(), createdSecret.GetName(), true)
 	}
 
 	// Update secrets count
 	generatedSecret.Status.SecretsCount = len(generatedSecret.Status.SecretsGeneratedRef.Secrets)
+	pruneTargetStatuses(&generatedSecret.Status, generatedSecret.GetTargetNamespaces())
 
 	// Set conditions
-	changed := false
-	if generatedSecret.Status.SecretsCount > 0 {
-		changed = meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionTrue, generatedsecretv1.ReasonSecretsGenerated, fmt.Sprintf("Successfully generated %d secret(s)", generatedSecret.Status.SecretsCount)))
-		changed = meta.RemoveStatusCondition(&generatedSecret.Status.Conditions, generatedsecretv1.ConditionError) || changed
+	if failed := failedTargetNamespaces(&generatedSecret.Status); len(failed) > 0 {
+		meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionError, metav1.ConditionTrue, generatedsecretv1.ReasonGenerationFailed, fmt.Sprintf("Failed to create secrets in namespaces: %s", strings.Join(failed, ", "))))
+		meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionFalse, generatedsecretv1.ReasonGenerationFailed, fmt.Sprintf("Created %d of %d secrets", generatedSecret.Status.SecretsCount, len(secrets))))
+	} else if generatedSecret.Status.SecretsCount > 0 {
+		meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionTrue, generatedsecretv1.ReasonSecretsGenerated, fmt.Sprintf("Successfully generated %d secret(s)", generatedSecret.Status.SecretsCount)))
+		meta.RemoveStatusCondition(&generatedSecret.Status.Conditions, generatedsecretv1.ConditionError)
 	}
 
-	if changed || initalLength != len(generatedSecret.Status.SecretsGeneratedRef.Secrets) {
+	if !equality.Semantic.DeepEqual(initalStatus, &generatedSecret.Status) {
 		return r.updateStatusOrRetry(ctx, &generatedSecret)
 	}
 	return nil
 }
 
-func secretIsListedInValidSecrets(validSecrets []corev1.Secret, secretCheck corev1.Secret) bool {
-	for _, secret := range validSecrets {
-		if secret.GetNamespace() == secretCheck.GetNamespace() && secret.GetName() == secretCheck.GetName() {
-			return true
-		}
+// indexSecrets returns the secrets keyed by their namespace and name
+func indexSecrets(secrets []corev1.Secret) map[types.NamespacedName]*corev1.Secret {
+	index := make(map[types.NamespacedName]*corev1.Secret, len(secrets))
+	for i := range secrets {
+		index[types.NamespacedName{Namespace: secrets[i].GetNamespace(), Name: secrets[i].GetName()}] = &secrets[i]
 	}
-	return false
+	return index
 }
 
-// generatePasswordSecrets
-func generatePasswordSecrets(generatedSecret generatedsecretv1.GeneratedSecret, data map[string][]byte) []corev1.Secret {
-	labels := generatedSecret.GetLabels()
-	if labels == nil {
-		labels = make(map[string]string)
-	}
-	annotations := generatedSecret.GetSecretAnnotations()
-	if annotations == nil {
-		annotations = make(map[string]string)
+// getSecretType returns the type of the target secrets. Unless the metadata sets a type, secrets that store a docker
+// config in .dockerconfigjson are of type kubernetes.io/dockerconfigjson, and other secrets are opaque.
+func getSecretType(generatedSecret generatedsecretv1.GeneratedSecret) corev1.SecretType {
+	if generatedSecret.Spec.Metadata.Type != "" {
+		return corev1.SecretType(generatedSecret.Spec.Metadata.Type)
 	}
-	// append ownership annotations
-	for k, v := range getLabelsForSecret(generatedSecret) {
-		labels[k] = v
+	if item, found := generatedSecret.Spec.Template.Data[corev1.DockerConfigJsonKey]; found && item.DockerConfig != nil {
+		return corev1.SecretTypeDockerConfigJson
 	}
+	return corev1.SecretTypeOpaque
+}
 
+// generatePasswordSecrets constructs the secret of every target with the given shared data.
+// Values that are specific to a target are generated afterwards, see renderTargetValues.
+func generatePasswordSecrets(generatedSecret generatedsecretv1.GeneratedSecret, data map[string][]byte) []corev1.Secret {
 	secrets := []corev1.Secret{}
-	sort.Strings(generatedSecret.Spec.Metadata.GetNamespaces())
 
-	secretType := corev1.SecretTypeOpaque
-	if generatedSecret.Spec.Metadata.Type != "" {
-		secretType = corev1.SecretType(generatedSecret.Spec.Metadata.Type)
-	}
+	secretType := getSecretType(generatedSecret)
+	for _, namespace := range generatedSecret.GetTargetNamespaces() {
+		labels := generatedSecret.GetTargetSecretLabels(namespace)
+		// append ownership labels
+		for k, v := range getLabelsForSecret(generatedSecret) {
+			labels[k] = v
+		}
 
-	for _, namespace := range generatedSecret.Spec.Metadata.GetNamespaces() {
 		secret := createsecret.ConstructSecret(createsecret.SecretOptions{
-			Name:        generatedSecret.GetSecretName(),
+			Name:        generatedSecret.GetTargetSecretName(namespace),
 			Namespace:   namespace,
 			Labels:      labels,
-			Annotations: annotations,
+			Annotations: generatedSecret.GetTargetSecretAnnotations(namespace),
 			Data:        data,
 		})
 
@@ -3,6 +3,7 @@ package generatedsecret
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
 	"github.com/containerinfra/kube-secrets-operator/pkg/generation/pwdgen"
@@ -21,11 +22,14 @@ func (r *GeneratedSecretReconciler) initalizeGeneratedSecret(ctx context.Context
 	logger := log.FromContext(ctx)
 
 	// Generate all secret values (static, generated, and templated)
-	passwordData, err := pwdgen.GenerateValues(ctx, r.Client, generatedSecret.Namespace, &generatedSecret.Spec.Template)
+	fetcher := pwdgen.NewCachingFetcher(r.uncachedReader())
+	renderContext := r.newRenderContext(&generatedSecret)
+	passwordData, inputs, err := pwdgen.GenerateValuesWithInputs(ctx, fetcher, &generatedSecret.Spec.Template, renderContext)
 	if err != nil {
 		// Set error conditions
-		changed := meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionError, metav1.ConditionTrue, generatedsecretv1.ReasonGenerationFailed, fmt.Sprintf("Failed to generate secret values: %v", err)))
-		changed = meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionFalse, generatedsecretv1.ReasonGenerationFailed, "Secret generation failed")) || changed
+		reason := generationFailureReason(err)
+		changed := meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionError, metav1.ConditionTrue, reason, fmt.Sprintf("Failed to generate secret values: %v", err)))
+		changed = meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionFalse, reason, "Secret generation failed")) || changed
 		if changed {
 			if err := r.updateStatusOrRetry(ctx, &generatedSecret); err != nil {
 				logger.Error(err, "Failed to update status with error condition")
@@ -34,48 +38,37 @@ func (r *GeneratedSecretReconciler) initalizeGeneratedSecret(ctx context.Context
 		return fmt.Errorf("failed to generate secret values: %w", err)
 	}
 
-	// Create the k8s secrets
+	// Create the k8s secrets, with the values that are specific to each target
 	secrets := generatePasswordSecrets(generatedSecret, passwordData)
+	renderErrs := r.renderTargetValues(ctx, &generatedSecret, fetcher, secrets, passwordData, renderContext, inputs)
+
+	index := secretIndex(secrets)
+	linked := make([]bool, len(secrets))
+	errs := r.writeSecrets(ctx, secrets, skipFailed(secrets, renderErrs, func(ctx context.Context, secret *corev1.Secret) error {
+		var err error
+		linked[index[secret]], err = r.createOrLinkSecret(ctx, generatedSecret, secret)
+		return err
+	}))
 
 	generatedSecretsRefs := []generatedsecretv1.GeneratedSecretRef{}
 	hasErrors := false
 	for i := range secrets {
 		secret := &secrets[i] // Use pointer to avoid copying
-		err := r.Client.Create(ctx, secret)
-		if err != nil {
-			if !errors.IsAlreadyExists(err) {
-				logger.Info(fmt.Sprintf("Failed to create secret: %v", err))
-				r.Recorder.Eventf(&generatedSecret, corev1.EventTypeWarning, "Failed secret create", "Error while attempting to create secret in namespace '%s': %s", secret.GetNamespace(), err.Error())
-				hasErrors = true
-				continue
-			} else {
-				logger.Info(fmt.Sprintf("A secret for %s in namespace %s already exists...", secret.GetName(), secret.GetNamespace()))
-
-				// Fetch the existing secret and use that instead
-				err := r.Client.Get(ctx, types.NamespacedName{
-					Name:      secret.GetName(),
-					Namespace: secret.GetNamespace(),
-				}, secret)
-				if err != nil {
-					r.Recorder.Eventf(&generatedSecret, corev1.EventTypeWarning, "Failed secret create", "Error while attempting to link secret in namespace '%s': %s", secret.GetNamespace(), err.Error())
-					hasErrors = true
-					continue
-				}
-			}
-		} else {
-			logger.Info("created secret", "namespace", secret.Namespace, "name", secret.Name, "uid", secret.UID)
-		}
-
-		// Verify we have the required metadata
-		if secret.UID == "" {
-			logger.Error(fmt.Errorf("secret UID is empty after create/get"), "secret metadata incomplete", "name", secret.Name, "namespace", secret.Namespace)
+		if errs[i] != nil {
+			r.Recorder.Eventf(&generatedSecret, corev1.EventTypeWarning, "Failed secret create", "Error while attempting to create secret in namespace '%s': %s", secret.GetNamespace(), errs[i].Error())
+			setTargetFailed(&generatedSecret.Status, secret.GetNamespace(), secret.GetName(), errs[i])
 			hasErrors = true
 			continue
 		}
 
 		ref := utils.GetGeneratedSecretRef(*secret)
 
-		r.Recorder.Eventf(&generatedSecret, corev1.EventTypeNormal, "Created secret", "Created a new secret in namespace '%s'", secret.GetNamespace())
+		if linked[i] {
+			r.Recorder.Eventf(&generatedSecret, corev1.EventTypeNormal, "Linked secret", "Linked the existing secret in namespace '%s'", secret.GetNamespace())
+		} else {
+			r.Recorder.Eventf(&generatedSecret, corev1.EventTypeNormal, "Created secret", "Created a new secret in namespace '%s'", secret.GetNamespace())
+		}
+		setTargetReady(&generatedSecret.Status, secret.GetNamespace(), secret.GetName(), true)
 		generatedSecretsRefs = append(generatedSecretsRefs, ref)
 	}
 
@@ -83,22 +76,66 @@ func (r *GeneratedSecretReconciler) initalizeGeneratedSecret(ctx context.Context
 	generatedSecret.Status.Initalized = true
 	generatedSecret.Status.SecretsGeneratedRef.Secrets = generatedSecretsRefs
 	generatedSecret.Status.SecretsCount = len(generatedSecretsRefs)
+	generatedSecret.Status.Templates = templateStatuses(inputs)
+	generatedSecret.Status.Entropy = entropyStatuses(&generatedSecret, generatedSecret.Status.Entropy, true)
+	pruneTargetStatuses(&generatedSecret.Status, generatedSecret.GetTargetNamespaces())
 
 	// Set conditions
-	changed := false
 	if hasErrors {
-		changed = meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionError, metav1.ConditionTrue, generatedsecretv1.ReasonGenerationFailed, "Some secrets failed to be created"))
-		changed = meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionFalse, generatedsecretv1.ReasonGenerationFailed, fmt.Sprintf("Created %d of %d secrets", len(generatedSecretsRefs), len(secrets)))) || changed
+		meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionError, metav1.ConditionTrue, generatedsecretv1.ReasonGenerationFailed, fmt.Sprintf("Failed to create secrets in namespaces: %s", strings.Join(failedTargetNamespaces(&generatedSecret.Status), ", "))))
+		meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionFalse, generatedsecretv1.ReasonGenerationFailed, fmt.Sprintf("Created %d of %d secrets", len(generatedSecretsRefs), len(secrets))))
 	} else {
 		// Clear error condition
-		changed = meta.RemoveStatusCondition(&generatedSecret.Status.Conditions, generatedsecretv1.ConditionError)
-		changed = meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionTrue, generatedsecretv1.ReasonSecretsGenerated, fmt.Sprintf("Successfully generated %d secret(s)", len(generatedSecretsRefs)))) || changed
+		meta.RemoveStatusCondition(&generatedSecret.Status.Conditions, generatedsecretv1.ConditionError)
+		meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionReady, metav1.ConditionTrue, generatedsecretv1.ReasonSecretsGenerated, fmt.Sprintf("Successfully generated %d secret(s)", len(generatedSecretsRefs))))
 	}
-	if changed {
-		if err := r.updateStatusOrRetry(ctx, &generatedSecret); err != nil {
-			logger.Error(err, "Failed to update status")
-			return err
-		}
+
+	// Secrets have been written, so the refs and target states always need to be persisted
+	if err := r.updateStatusOrRetry(ctx, &generatedSecret); err != nil {
+		logger.Error(err, "Failed to update status")
+		return err
 	}
 	return nil
 }
+
+// createOrLinkSecret creates the secret, or links the secret if it already exists and is managed by the GeneratedSecret,
+// e.g. when the status was lost. Secrets created by hand or managed by another GeneratedSecret are never adopted, as
+// they would be deleted along with this GeneratedSecret.
+// The secret is updated in place with the object as stored by the API server, linked is true when it already existed.
+func (r *GeneratedSecretReconciler) createOrLinkSecret(ctx context.Context, generatedSecret generatedsecretv1.GeneratedSecret, secret *corev1.Secret) (linked bool, err error) {
+	logger := log.FromContext(ctx)
+
+	err = r.Client.Create(ctx, secret)
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			logger.Info(fmt.Sprintf("Failed to create secret: %v", err))
+			return false, err
+		}
+		logger.Info(fmt.Sprintf("A secret for %s in namespace %s already exists...", secret.GetName(), secret.GetNamespace()))
+
+		// Fetch the existing secret, it is not in the cache when it is not managed by the operator
+		existing := &corev1.Secret{}
+		err := r.uncachedReader().Get(ctx, types.NamespacedName{
+			Name:      secret.GetName(),
+			Namespace: secret.GetNamespace(),
+		}, existing)
+		if err != nil {
+			return false, fmt.Errorf("failed to link existing secret: %w", err)
+		}
+		if !isSecretOwnedBy(generatedSecret, *existing) {
+			return false, fmt.Errorf("secret %s/%s already exists and is not managed by this GeneratedSecret", existing.GetNamespace(), existing.GetName())
+		}
+		*secret = *existing
+		linked = true
+	} else {
+		logger.Info("created secret", "namespace", secret.Namespace, "name", secret.Name, "uid", secret.UID)
+	}
+
+	// Verify we have the required metadata
+	if secret.UID == "" {
+		err := fmt.Errorf("secret UID is empty after create/get")
+		logger.Error(err, "secret metadata incomplete", "name", secret.Name, "namespace", secret.Namespace)
+		return linked, err
+	}
+	return linked, nil
+}
@@ -0,0 +1,139 @@
+package generatedsecret
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/pwdgen"
+)
+
+// templateInputIndexKey is the field index of GeneratedSecrets by the secrets and config maps their templates read from
+const templateInputIndexKey = "spec.template.inputs"
+
+// templateInputIndexValue returns the value under which a template input is indexed
+func templateInputIndexValue(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// indexTemplateInputs returns the index values of all template inputs of a GeneratedSecret
+func indexTemplateInputs(obj client.Object) []string {
+	generatedSecret, ok := obj.(*generatedsecretv1.GeneratedSecret)
+	if !ok {
+		return nil
+	}
+	namespaceOrDefault := func(namespace string) string {
+		if namespace == "" {
+			return generatedSecret.Namespace
+		}
+		return namespace
+	}
+
+	items := []generatedsecretv1.SecretValueItemTemplate{}
+	for _, item := range generatedSecret.Spec.Template.Data {
+		items = append(items, item)
+	}
+	for _, target := range generatedSecret.Spec.Targets {
+		for _, item := range target.Data {
+			items = append(items, item)
+		}
+	}
+
+	templates := []*generatedsecretv1.TemplatedValueSpec{}
+	for _, item := range items {
+		if item.Templated != nil {
+			templates = append(templates, item.Templated)
+		}
+	}
+	if document := generatedSecret.Spec.Template.Document; document != nil {
+		templates = append(templates, &document.TemplatedValueSpec)
+	}
+
+	values := map[string]struct{}{}
+	for _, template := range templates {
+		if ref := template.InputSecretRef; ref != nil {
+			values[templateInputIndexValue(pwdgen.InputKindSecret, namespaceOrDefault(ref.Namespace), ref.Name)] = struct{}{}
+		}
+		for _, input := range template.Inputs {
+			if ref := input.SecretRef; ref != nil {
+				values[templateInputIndexValue(pwdgen.InputKindSecret, namespaceOrDefault(ref.Namespace), ref.Name)] = struct{}{}
+			}
+			if ref := input.ConfigMapRef; ref != nil {
+				values[templateInputIndexValue(pwdgen.InputKindConfigMap, namespaceOrDefault(ref.Namespace), ref.Name)] = struct{}{}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for value := range values {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// requestsForInput returns a map function that enqueues every GeneratedSecret that uses the input in a template
+func (r *GeneratedSecretReconciler) requestsForInput(kind string) func(ctx context.Context, obj *metav1.PartialObjectMetadata) []reconcile.Request {
+	return func(ctx context.Context, obj *metav1.PartialObjectMetadata) []reconcile.Request {
+		generatedSecrets := &generatedsecretv1.GeneratedSecretList{}
+		if err := r.Client.List(ctx, generatedSecrets, client.MatchingFields{templateInputIndexKey: templateInputIndexValue(kind, obj.GetNamespace(), obj.GetName())}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list GeneratedSecrets for template input", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(generatedSecrets.Items))
+		for _, generatedSecret := range generatedSecrets.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&generatedSecret)})
+		}
+		return requests
+	}
+}
+
+// inputChangedPredicate passes new inputs and changes to existing inputs. Deleted inputs keep the last rendered values.
+var inputChangedPredicate = predicate.TypedFuncs[*metav1.PartialObjectMetadata]{
+	CreateFunc: func(e event.TypedCreateEvent[*metav1.PartialObjectMetadata]) bool {
+		return true
+	},
+	UpdateFunc: func(e event.TypedUpdateEvent[*metav1.PartialObjectMetadata]) bool {
+		return e.ObjectOld.GetResourceVersion() != e.ObjectNew.GetResourceVersion()
+	},
+	DeleteFunc: func(e event.TypedDeleteEvent[*metav1.PartialObjectMetadata]) bool {
+		return false
+	},
+	GenericFunc: func(e event.TypedGenericEvent[*metav1.PartialObjectMetadata]) bool {
+		return false
+	},
+}
+
+// inputMetadata returns the metadata-only object used to watch template inputs of the given kind
+func inputMetadata(kind string) *metav1.PartialObjectMetadata {
+	input := &metav1.PartialObjectMetadata{}
+	input.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(kind))
+	return input
+}
+
+// TransformInputMetadata strips watched template inputs down to the metadata needed to find the GeneratedSecrets using them
+func TransformInputMetadata(obj any) (any, error) {
+	input, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		return obj, nil
+	}
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: input.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            input.Name,
+			Namespace:       input.Namespace,
+			UID:             input.UID,
+			ResourceVersion: input.ResourceVersion,
+		},
+	}, nil
+}
@@ -12,6 +12,12 @@ import (
 func (r *GeneratedSecretReconciler) reconcileGeneratedSecrets(ctx context.Context, generatedSecret generatedsecretv1.GeneratedSecret) error {
 	logger := log.FromContext(ctx)
 
+	// List all secrets managed by this GeneratedSecret once, every target is evaluated against this snapshot
+	managedSecrets, err := r.listManagedSecrets(ctx, generatedSecret)
+	if err != nil {
+		return err
+	}
+
 	// Figure out if any secret is in an invalid state and resync if necessary
 	// An incorrect state is:
 	//  - not the expected UID
@@ -19,9 +25,12 @@ func (r *GeneratedSecretReconciler) reconcileGeneratedSecrets(ctx context.Contex
 	//  - different resource type
 	// If incorrect, see if we have any correct ones. If so, compare data and update any necessary values (i.g. the data field, labels, annotations, type)
 	// if we cannot find any correct / valid secrets; mark this secret as invalid, report errors through k8s events -> support sending notifications through webhooks, maybe?
-	validSecrets, invalidSecrets := r.fetchExistingSecrets(ctx, generatedSecret)
-	if len(invalidSecrets) != 0 {
-		for _, secret := range invalidSecrets {
+	targets := r.fetchExistingSecrets(ctx, generatedSecret, managedSecrets)
+	if err := r.reportStaleSecrets(ctx, &generatedSecret, targets.stale); err != nil {
+		return err
+	}
+	if len(targets.invalid) != 0 {
+		for _, secret := range targets.invalid {
 			logger.Error(fmt.Errorf("secret has been externally modified: secret '%s' in namespace '%s'", secret.GetName(), secret.GetNamespace()), "error")
 		}
 
@@ -30,7 +39,7 @@ func (r *GeneratedSecretReconciler) reconcileGeneratedSecrets(ctx context.Contex
 		for _, secretRef := range generatedSecret.Status.SecretsGeneratedRef.Secrets {
 
 			isValid := true
-			for _, invalidSecret := range invalidSecrets {
+			for _, invalidSecret := range targets.invalid {
 				if secretRef.Namespace == invalidSecret.GetNamespace() && secretRef.Name == invalidSecret.GetName() {
 					isValid = false // found in the invalid secrets list
 					break
@@ -50,14 +59,29 @@ func (r *GeneratedSecretReconciler) reconcileGeneratedSecrets(ctx context.Contex
 		}
 	}
 
-	if len(validSecrets) == 0 && len(invalidSecrets) == 0 {
+	if len(targets.valid) == 0 && len(targets.invalid) == 0 {
 		return r.initalizeGeneratedSecret(ctx, generatedSecret)
 	}
+	if len(targets.missing) != 0 {
+		logger.Info("secrets are missing in target namespaces", "namespaces", targets.missing)
+	}
 
 	// Update metadata if necessary
-	if r.reconcileToSpec(ctx, generatedSecret) {
-		return nil
+	validSecrets := r.reconcileToSpec(ctx, &generatedSecret, targets.valid)
+	if len(validSecrets) == 0 {
+		// Only secrets that were modified externally are left. They are managed by this GeneratedSecret, so they are
+		// linked again as they are, as is done for every invalid secret next to a valid one.
+		validSecrets = r.reconcileToSpec(ctx, &generatedSecret, targets.invalid)
+	}
+	if len(validSecrets) == 0 {
+		// None of the existing secrets hold usable data anymore, start over
+		return r.initalizeGeneratedSecret(ctx, generatedSecret)
 	}
-	return r.createMissingPasswordSecrets(ctx, generatedSecret, validSecrets)
 
+	// Render templated values again if their inputs changed
+	validSecrets, sharedData, err := r.rerenderTemplates(ctx, &generatedSecret, validSecrets)
+	if err != nil {
+		return err
+	}
+	return r.createMissingPasswordSecrets(ctx, generatedSecret, validSecrets, sharedData)
 }
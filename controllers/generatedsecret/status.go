@@ -0,0 +1,132 @@
+package generatedsecret
+
+import (
+	"errors"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/templated"
+)
+
+// setTargetReady marks the secret in the given namespace as in sync. The sync time is only bumped when the secret
+// was written or when the target transitions to ready, so verifying an unchanged secret does not dirty the status.
+func setTargetReady(status *generatedsecretv1.GeneratedSecretStatus, namespace, name string, written bool) {
+	target := generatedsecretv1.TargetStatus{
+		Namespace: namespace,
+		Name:      name,
+		State:     generatedsecretv1.TargetStateReady,
+	}
+	existing := findTargetStatus(status, namespace)
+	if written || existing == nil || existing.State != generatedsecretv1.TargetStateReady || existing.LastSyncTime == nil {
+		now := metav1.Now()
+		target.LastSyncTime = &now
+	} else {
+		target.LastSyncTime = existing.LastSyncTime
+	}
+	setTargetStatus(status, target)
+}
+
+// setTargetFailed marks the secret in the given namespace as failed with the given error.
+// The last sync time is kept, so it keeps pointing at the last successful sync.
+func setTargetFailed(status *generatedsecretv1.GeneratedSecretStatus, namespace, name string, err error) {
+	target := generatedsecretv1.TargetStatus{
+		Namespace: namespace,
+		Name:      name,
+		State:     generatedsecretv1.TargetStateFailed,
+		LastError: err.Error(),
+	}
+	if existing := findTargetStatus(status, namespace); existing != nil {
+		target.LastSyncTime = existing.LastSyncTime
+	}
+	setTargetStatus(status, target)
+}
+
+func setTargetStatus(status *generatedsecretv1.GeneratedSecretStatus, target generatedsecretv1.TargetStatus) {
+	if existing := findTargetStatus(status, target.Namespace); existing != nil {
+		*existing = target
+		return
+	}
+	status.Targets = append(status.Targets, target)
+	sort.Slice(status.Targets, func(i, j int) bool {
+		return status.Targets[i].Namespace < status.Targets[j].Namespace
+	})
+}
+
+func findTargetStatus(status *generatedsecretv1.GeneratedSecretStatus, namespace string) *generatedsecretv1.TargetStatus {
+	for i := range status.Targets {
+		if status.Targets[i].Namespace == namespace {
+			return &status.Targets[i]
+		}
+	}
+	return nil
+}
+
+// pruneTargetStatuses removes the status of targets that are no longer part of the spec
+func pruneTargetStatuses(status *generatedsecretv1.GeneratedSecretStatus, namespaces []string) {
+	wanted := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		wanted[namespace] = true
+	}
+	targets := []generatedsecretv1.TargetStatus{}
+	for _, target := range status.Targets {
+		if wanted[target.Namespace] {
+			targets = append(targets, target)
+		}
+	}
+	status.Targets = targets
+}
+
+// setStaleSecrets records the stale secrets in the status, and returns the secrets that were not stale before
+func setStaleSecrets(status *generatedsecretv1.GeneratedSecretStatus, secrets []corev1.Secret) []generatedsecretv1.SecretReference {
+	previous := map[generatedsecretv1.SecretReference]bool{}
+	for _, ref := range status.StaleSecrets {
+		previous[ref] = true
+	}
+	refs := []generatedsecretv1.SecretReference{}
+	added := []generatedsecretv1.SecretReference{}
+	for _, secret := range secrets {
+		ref := generatedsecretv1.SecretReference{Namespace: secret.GetNamespace(), Name: secret.GetName()}
+		refs = append(refs, ref)
+		if !previous[ref] {
+			added = append(added, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Namespace != refs[j].Namespace {
+			return refs[i].Namespace < refs[j].Namespace
+		}
+		return refs[i].Name < refs[j].Name
+	})
+	if len(refs) == 0 {
+		refs = nil
+	}
+	status.StaleSecrets = refs
+	return added
+}
+
+// failedTargetNamespaces returns the namespaces of all targets that failed to sync
+func failedTargetNamespaces(status *generatedsecretv1.GeneratedSecretStatus) []string {
+	namespaces := []string{}
+	for _, target := range status.Targets {
+		if target.State == generatedsecretv1.TargetStateFailed {
+			namespaces = append(namespaces, target.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// generationFailureReason returns the condition reason for an error returned while generating or rendering values
+func generationFailureReason(err error) string {
+	var templateErr *templated.Error
+	switch {
+	case errors.As(err, &templateErr):
+		return generatedsecretv1.ReasonTemplateError
+	case apierrors.IsNotFound(err):
+		return generatedsecretv1.ReasonInputSecretNotFound
+	}
+	return generatedsecretv1.ReasonGenerationFailed
+}
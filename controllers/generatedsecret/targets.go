@@ -0,0 +1,181 @@
+package generatedsecret
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+)
+
+// maxTargetObjectNamePrefix keeps generated GeneratedSecretTarget names within the 253 character limit
+const maxTargetObjectNamePrefix = 200
+
+// listTargetObjects returns all GeneratedSecretTarget objects that belong to the GeneratedSecret
+func (r *GeneratedSecretReconciler) listTargetObjects(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret) ([]generatedsecretv1.GeneratedSecretTarget, error) {
+	targets := &generatedsecretv1.GeneratedSecretTargetList{}
+	if err := r.Client.List(ctx, targets, client.InNamespace(generatedSecret.Namespace), client.MatchingLabels(getLabelsForSecret(*generatedSecret))); err != nil {
+		return nil, fmt.Errorf("failed to list GeneratedSecretTargets: %w", err)
+	}
+	return targets.Items, nil
+}
+
+// loadTargets populates the secret references and target states of the status from the GeneratedSecretTarget
+// objects, so the rest of the reconciler can work on the status regardless of the tracking mode.
+// When switching between modes the side that holds any state wins, after which syncTargetObjects moves it over.
+func (r *GeneratedSecretReconciler) loadTargets(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret) error {
+	objects, err := r.listTargetObjects(ctx, generatedSecret)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+	if !generatedSecret.TracksTargetsInObjects() && len(generatedSecret.Status.SecretsGeneratedRef.Secrets) != 0 {
+		return nil
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Spec.Namespace < objects[j].Spec.Namespace
+	})
+	refs := []generatedsecretv1.GeneratedSecretRef{}
+	targets := []generatedsecretv1.TargetStatus{}
+	for _, object := range objects {
+		if object.Status.SecretRef != nil {
+			refs = append(refs, *object.Status.SecretRef)
+		}
+		if object.Status.State != "" {
+			targets = append(targets, generatedsecretv1.TargetStatus{
+				Namespace:    object.Spec.Namespace,
+				Name:         object.Spec.Name,
+				State:        object.Status.State,
+				LastError:    object.Status.LastError,
+				LastSyncTime: object.Status.LastSyncTime,
+			})
+		}
+	}
+	generatedSecret.Status.SecretsGeneratedRef.Secrets = refs
+	generatedSecret.Status.Targets = targets
+	return nil
+}
+
+// syncTargetObjects makes the GeneratedSecretTarget objects reflect the secret references and target states of the status.
+// Objects are only written when they changed, and all of them are removed when the state is tracked in the status.
+func (r *GeneratedSecretReconciler) syncTargetObjects(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret) error {
+	logger := log.FromContext(ctx)
+
+	existing, err := r.listTargetObjects(ctx, generatedSecret)
+	if err != nil {
+		return err
+	}
+	existingByNamespace := map[string]generatedsecretv1.GeneratedSecretTarget{}
+	for _, object := range existing {
+		existingByNamespace[object.Spec.Namespace] = object
+	}
+
+	desired := map[string]*generatedsecretv1.GeneratedSecretTarget{}
+	if generatedSecret.TracksTargetsInObjects() {
+		desired = desiredTargetObjects(generatedSecret)
+	}
+
+	for namespace, object := range desired {
+		current, found := existingByNamespace[namespace]
+		if !found {
+			if err := controllerutil.SetControllerReference(generatedSecret, object, r.Scheme); err != nil {
+				return fmt.Errorf("failed to set owner of GeneratedSecretTarget: %w", err)
+			}
+			if err := r.Client.Create(ctx, object); err != nil {
+				return fmt.Errorf("failed to create GeneratedSecretTarget for namespace %s: %w", namespace, err)
+			}
+			continue
+		}
+		if equality.Semantic.DeepEqual(current.Spec, object.Spec) && equality.Semantic.DeepEqual(current.Status, object.Status) {
+			continue
+		}
+		current.Spec = object.Spec
+		current.Status = object.Status
+		if err := r.Client.Update(ctx, &current); err != nil {
+			return fmt.Errorf("failed to update GeneratedSecretTarget for namespace %s: %w", namespace, err)
+		}
+	}
+
+	for namespace, object := range existingByNamespace {
+		if _, found := desired[namespace]; found {
+			continue
+		}
+		if err := r.Client.Delete(ctx, &object); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete GeneratedSecretTarget for namespace %s: %w", namespace, err)
+		}
+		logger.Info("deleted GeneratedSecretTarget", "name", object.Name, "targetNamespace", namespace)
+	}
+	return nil
+}
+
+// desiredTargetObjects builds a GeneratedSecretTarget for each namespace that has a secret reference or target state
+func desiredTargetObjects(generatedSecret *generatedsecretv1.GeneratedSecret) map[string]*generatedsecretv1.GeneratedSecretTarget {
+	objects := map[string]*generatedsecretv1.GeneratedSecretTarget{}
+	objectFor := func(namespace, name string) *generatedsecretv1.GeneratedSecretTarget {
+		if object, found := objects[namespace]; found {
+			return object
+		}
+		object := &generatedsecretv1.GeneratedSecretTarget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      getTargetObjectName(generatedSecret.Name, namespace),
+				Namespace: generatedSecret.Namespace,
+				Labels:    getLabelsForSecret(*generatedSecret),
+			},
+			Spec: generatedsecretv1.GeneratedSecretTargetSpec{
+				GeneratedSecretName: generatedSecret.Name,
+				Namespace:           namespace,
+				Name:                name,
+			},
+		}
+		objects[namespace] = object
+		return object
+	}
+
+	for i := range generatedSecret.Status.SecretsGeneratedRef.Secrets {
+		ref := generatedSecret.Status.SecretsGeneratedRef.Secrets[i]
+		objectFor(ref.Namespace, ref.Name).Status.SecretRef = &ref
+	}
+	for _, target := range generatedSecret.Status.Targets {
+		object := objectFor(target.Namespace, target.Name)
+		object.Status.State = target.State
+		object.Status.LastError = target.LastError
+		object.Status.LastSyncTime = target.LastSyncTime
+	}
+	return objects
+}
+
+// getTargetObjectName returns a stable, unique name for the GeneratedSecretTarget of the given target namespace
+func getTargetObjectName(generatedSecretName, namespace string) string {
+	hash := sha256.Sum256([]byte(namespace))
+	prefix := generatedSecretName
+	if len(prefix) > maxTargetObjectNamePrefix {
+		prefix = prefix[:maxTargetObjectNamePrefix]
+	}
+	return fmt.Sprintf("%s-%s", prefix, hex.EncodeToString(hash[:])[:10])
+}
+
+// summarizeTargets updates the aggregated target counters of the status
+func summarizeTargets(status *generatedsecretv1.GeneratedSecretStatus) {
+	status.ReadyTargets = 0
+	status.FailedTargets = 0
+	for _, target := range status.Targets {
+		switch target.State {
+		case generatedsecretv1.TargetStateReady:
+			status.ReadyTargets++
+		case generatedsecretv1.TargetStateFailed:
+			status.FailedTargets++
+		}
+	}
+}
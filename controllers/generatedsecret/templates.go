@@ -0,0 +1,368 @@
+package generatedsecret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/pwdgen"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/templated"
+	"github.com/containerinfra/kube-secrets-operator/pkg/utils"
+)
+
+// rerenderTemplates renders the templated values again when one of their inputs changed since they were last rendered,
+// and writes the new values to the valid secrets. The valid secrets are returned as they are stored after the update,
+// together with the values shared by all targets.
+// Values that were rendered before input versions were recorded are kept, only their input versions are recorded.
+func (r *GeneratedSecretReconciler) rerenderTemplates(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret, validSecrets []corev1.Secret) ([]corev1.Secret, map[string][]byte, error) {
+	logger := log.FromContext(ctx)
+
+	if len(validSecrets) == 0 {
+		return validSecrets, nil, nil
+	}
+
+	recorded := map[string][]generatedsecretv1.TemplateInputVersion{}
+	for _, template := range generatedSecret.Status.Templates {
+		recorded[template.Key] = template.Inputs
+	}
+	fetcher := pwdgen.NewCachingFetcher(r.uncachedReader())
+	renderContext := r.newRenderContext(generatedSecret)
+	renderContext.Filter = func(key string, versions []generatedsecretv1.TemplateInputVersion) bool {
+		previous, found := recorded[key]
+		return found && !equality.Semantic.DeepEqual(previous, versions)
+	}
+
+	// Render the values shared by all targets
+	current, err := sharedValues(generatedSecret, validSecrets)
+	var data map[string][]byte
+	var inputs pwdgen.RenderedInputs
+	if err == nil {
+		data, inputs, err = pwdgen.RenderTemplatedValues(ctx, fetcher, &generatedSecret.Spec.Template, current, renderContext)
+	}
+	if err != nil {
+		r.Recorder.Eventf(generatedSecret, corev1.EventTypeWarning, "Failed template render", "Error while rendering templated values: %s", err.Error())
+		if meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionError, metav1.ConditionTrue, generationFailureReason(err), fmt.Sprintf("Failed to render templated values: %v", err))) {
+			if err := r.updateStatusOrRetry(ctx, generatedSecret); err != nil {
+				logger.Error(err, "Failed to update status with error condition")
+			}
+		}
+		return nil, nil, fmt.Errorf("failed to render templated values: %w", err)
+	}
+	for key, value := range data {
+		if !bytes.Equal(current[key], value) {
+			renderContext.Changed = append(renderContext.Changed, key)
+		}
+	}
+
+	// Apply the shared values to every valid secret, and generate the values of each target on top of them
+	secrets := make([]corev1.Secret, len(validSecrets))
+	for i := range validSecrets {
+		secrets[i] = *validSecrets[i].DeepCopy()
+	}
+	renderErrs := r.renderTargetValues(ctx, generatedSecret, fetcher, secrets, data, renderContext, inputs)
+	templates := templateStatuses(inputs)
+	entropy := entropyStatuses(generatedSecret, generatedSecret.Status.Entropy, false)
+
+	failed := false
+	changedKeys := map[string]struct{}{}
+	outdatedSecrets := []corev1.Secret{}
+	for i := range secrets {
+		if renderErrs[i] != nil {
+			logger.Info(fmt.Sprintf("Failed to render templated values of secret: %v", renderErrs[i]), "namespace", secrets[i].GetNamespace(), "name", secrets[i].GetName())
+			setTargetFailed(&generatedSecret.Status, secrets[i].GetNamespace(), secrets[i].GetName(), renderErrs[i])
+			failed = true
+			continue
+		}
+		outdated := false
+		for key, value := range secrets[i].Data {
+			if previous, found := validSecrets[i].Data[key]; !found || !bytes.Equal(previous, value) {
+				changedKeys[key] = struct{}{}
+				outdated = true
+			}
+		}
+		if outdated {
+			outdatedSecrets = append(outdatedSecrets, secrets[i])
+		}
+	}
+
+	if len(outdatedSecrets) == 0 && !failed {
+		if !equality.Semantic.DeepEqual(generatedSecret.Status.Templates, templates) || !equality.Semantic.DeepEqual(generatedSecret.Status.Entropy, entropy) {
+			generatedSecret.Status.Templates = templates
+			generatedSecret.Status.Entropy = entropy
+			if err := r.updateStatusOrRetry(ctx, generatedSecret); err != nil {
+				return nil, nil, err
+			}
+		}
+		return validSecrets, data, nil
+	}
+
+	keys := make([]string, 0, len(changedKeys))
+	for key := range changedKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	logger.Info("templated values changed, updating secrets", "keys", keys, "secrets", len(outdatedSecrets))
+	// The update response carries the new ResourceVersion, which is stored in the secret references below
+	errs := r.writeSecrets(ctx, outdatedSecrets, func(ctx context.Context, secret *corev1.Secret) error {
+		return r.Client.Update(ctx, secret)
+	})
+
+	updatedByKey := map[types.NamespacedName]*corev1.Secret{}
+	for i := range outdatedSecrets {
+		secret := &outdatedSecrets[i]
+		if errs[i] != nil {
+			logger.Info(fmt.Sprintf("Failed to update templated values of secret: %v", errs[i]), "namespace", secret.GetNamespace(), "name", secret.GetName())
+			setTargetFailed(&generatedSecret.Status, secret.GetNamespace(), secret.GetName(), errs[i])
+			failed = true
+			continue
+		}
+		updatedByKey[types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}] = secret
+		setTargetReady(&generatedSecret.Status, secret.GetNamespace(), secret.GetName(), true)
+	}
+
+	result := make([]corev1.Secret, 0, len(validSecrets))
+	for _, secret := range validSecrets {
+		if updated, found := updatedByKey[types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}]; found {
+			secret = *updated
+		}
+		result = append(result, secret)
+	}
+	for i, ref := range generatedSecret.Status.SecretsGeneratedRef.Secrets {
+		if updated, found := updatedByKey[types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}]; found {
+			generatedSecret.Status.SecretsGeneratedRef.Secrets[i] = utils.GetGeneratedSecretRef(*updated)
+		}
+	}
+	if len(updatedByKey) > 0 {
+		r.Recorder.Eventf(generatedSecret, corev1.EventTypeNormal, "Rendered templates", "Updated keys %s in %d secret(s) after an input or override changed", strings.Join(keys, ", "), len(updatedByKey))
+	}
+
+	generatedSecret.Status.Entropy = entropy
+	// Only record the new input versions once every secret holds the new values, so failed secrets are retried
+	if !failed {
+		generatedSecret.Status.Templates = templates
+	} else {
+		meta.SetStatusCondition(&generatedSecret.Status.Conditions, generatedSecret.NewCondition(generatedsecretv1.ConditionError, metav1.ConditionTrue, generatedsecretv1.ReasonGenerationFailed, fmt.Sprintf("Failed to update templated values in namespaces: %s", strings.Join(failedTargetNamespaces(&generatedSecret.Status), ", "))))
+	}
+	if err := r.updateStatusOrRetry(ctx, generatedSecret); err != nil {
+		return nil, nil, err
+	}
+	if failed {
+		return nil, nil, fmt.Errorf("failed to update templated values in secrets in namespaces: %s", strings.Join(failedTargetNamespaces(&generatedSecret.Status), ", "))
+	}
+	return result, data, nil
+}
+
+// sharedValues returns the values shared by all targets, as held by the valid secrets. A value is only taken from
+// targets that share the key. Targets without overrides take precedence, and otherwise
+// the value most targets agree on wins, so a target whose override was just removed does not overrule the others.
+// Static and generated values that no target shares are generated, templated values are left to be rendered.
+func sharedValues(generatedSecret *generatedsecretv1.GeneratedSecret, validSecrets []corev1.Secret) (map[string][]byte, error) {
+	type candidate struct {
+		value []byte
+		votes int
+	}
+	candidates := map[string]map[string]*candidate{}
+	best := map[string]*candidate{}
+	for _, secret := range validSecrets {
+		scope, err := pwdgen.KeyScopes(&generatedSecret.Spec.Template, generatedSecret.GetTargetOverrides(secret.GetNamespace()))
+		if err != nil {
+			return nil, err
+		}
+		votes := 1
+		if generatedSecret.GetTarget(secret.GetNamespace()) == nil {
+			votes = len(validSecrets) + 1
+		}
+		for key, value := range secret.Data {
+			if scope(key) != pwdgen.KeyShared {
+				continue
+			}
+			if candidates[key] == nil {
+				candidates[key] = map[string]*candidate{}
+			}
+			c := candidates[key][string(value)]
+			if c == nil {
+				c = &candidate{value: value}
+				candidates[key][string(value)] = c
+			}
+			c.votes += votes
+			if best[key] == nil || c.votes > best[key].votes {
+				best[key] = c
+			}
+		}
+	}
+
+	values := make(map[string][]byte, len(best))
+	for key, c := range best {
+		values[key] = c.value
+	}
+	return pwdgen.GenerateMissingValues(&generatedSecret.Spec.Template, values)
+}
+
+// newRenderContext returns the context to render the templated values of the GeneratedSecret in
+func (r *GeneratedSecretReconciler) newRenderContext(generatedSecret *generatedsecretv1.GeneratedSecret) pwdgen.RenderContext {
+	return pwdgen.RenderContext{
+		GeneratedSecret: types.NamespacedName{Namespace: generatedSecret.Namespace, Name: generatedSecret.Name},
+		Limits:          r.TemplateLimits,
+	}
+}
+
+// renderTargetValues sets the shared values on every secret, and generates the values that are specific to the target
+// of the secret on top of them, see pwdgen.KeyScopes.
+// The secret data is replaced, and the versions of the inputs the values were rendered from are added to inputs.
+// Inputs of overridden values are recorded per namespace, as they differ between targets.
+// The error of each secret is returned at the index of the secret.
+func (r *GeneratedSecretReconciler) renderTargetValues(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret, fetcher pwdgen.SecretFetcher, secrets []corev1.Secret, shared map[string][]byte, renderContext pwdgen.RenderContext, inputs pwdgen.RenderedInputs) []error {
+	errs := make([]error, len(secrets))
+	renderContext.Shared = shared
+
+	for i := range secrets {
+		secret := &secrets[i]
+		overrides := generatedSecret.GetTargetOverrides(secret.GetNamespace())
+		scope, err := pwdgen.KeyScopes(&generatedSecret.Spec.Template, overrides)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		data := make(map[string][]byte, len(secret.Data))
+		for key, value := range secret.Data {
+			data[key] = value
+		}
+		for key, value := range shared {
+			if scope(key) == pwdgen.KeyShared {
+				data[key] = value
+			}
+		}
+
+		inputKey := func(key string) string {
+			if _, overridden := overrides[key]; overridden {
+				return targetInputKey(secret.GetNamespace(), key)
+			}
+			return key
+		}
+		targetContext := renderContext
+		if renderContext.Filter != nil {
+			targetContext.Filter = func(key string, versions []generatedsecretv1.TemplateInputVersion) bool {
+				return renderContext.Filter(inputKey(key), versions)
+			}
+		}
+		targetContext.Target, err = r.getTemplateTarget(ctx, generatedSecret, overrides, secret.GetNamespace(), secret.GetName())
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		data, targetInputs, err := pwdgen.GenerateTargetValues(ctx, fetcher, &generatedSecret.Spec.Template, overrides, data, targetContext)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		secret.Data = data
+		for key, versions := range targetInputs {
+			inputs[inputKey(key)] = versions
+		}
+	}
+	return errs
+}
+
+// targetInputKey returns the key under which the inputs of a value overridden in the given namespace are recorded.
+// Secret keys cannot contain a slash, so these never clash with the keys of shared values.
+func targetInputKey(namespace, key string) string {
+	return namespace + "/" + key
+}
+
+// getTemplateTarget returns the template context of the target secret with the given namespace and name.
+// The target namespace is only fetched when a template of the target refers to .Target
+func (r *GeneratedSecretReconciler) getTemplateTarget(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret, overrides generatedsecretv1.SecretValueItems, namespace, name string) (*templated.Target, error) {
+	refersToTarget, err := pwdgen.RefersToTarget(&generatedSecret.Spec.Template, overrides)
+	if err != nil {
+		return nil, err
+	}
+	if !refersToTarget {
+		return &templated.Target{Namespace: namespace, Name: name}, nil
+	}
+
+	targetNamespace := &corev1.Namespace{}
+	if err := r.uncachedReader().Get(ctx, types.NamespacedName{Name: namespace}, targetNamespace); err != nil {
+		return nil, fmt.Errorf("failed to fetch target namespace %s: %w", namespace, err)
+	}
+	return &templated.Target{
+		Namespace:   namespace,
+		Name:        name,
+		Labels:      targetNamespace.GetLabels(),
+		Annotations: targetNamespace.GetAnnotations(),
+	}, nil
+}
+
+// templateStatuses converts the rendered inputs to the status of each templated key, sorted by key
+func templateStatuses(inputs pwdgen.RenderedInputs) []generatedsecretv1.TemplateStatus {
+	if len(inputs) == 0 {
+		return nil
+	}
+	templates := make([]generatedsecretv1.TemplateStatus, 0, len(inputs))
+	for key, versions := range inputs {
+		templates = append(templates, generatedsecretv1.TemplateStatus{
+			Key:    key,
+			Inputs: versions,
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Key < templates[j].Key
+	})
+	return templates
+}
+
+// entropyStatuses returns the entropy of each generated key, sorted by key. Recorded entries are kept, as values are
+// not generated again when their spec changes, and keys without an entry are recorded with the entropy their current
+// spec guarantees. Keys that are no longer generated are dropped.
+// When the values may have been generated again, as on initialization, where existing secrets are linked rather than
+// replaced, the lower of the recorded and the current entropy is kept, so the entropy is never overstated.
+func entropyStatuses(generatedSecret *generatedsecretv1.GeneratedSecret, recorded []generatedsecretv1.EntropyStatus, regenerated bool) []generatedsecretv1.EntropyStatus {
+	current := map[string]int{}
+	for key, bits := range pwdgen.GeneratedEntropy(&generatedSecret.Spec.Template, nil) {
+		current[key] = bits
+	}
+	for _, target := range generatedSecret.Spec.Targets {
+		if len(target.Data) == 0 {
+			continue
+		}
+		scope, err := pwdgen.KeyScopes(&generatedSecret.Spec.Template, target.Data)
+		if err != nil {
+			continue
+		}
+		for key, bits := range pwdgen.GeneratedEntropy(&generatedSecret.Spec.Template, target.Data) {
+			if scope(key) == pwdgen.KeyTargetSpecific {
+				current[targetInputKey(target.Namespace, key)] = bits
+			}
+		}
+	}
+	if len(current) == 0 {
+		return nil
+	}
+
+	previous := make(map[string]int32, len(recorded))
+	for _, entry := range recorded {
+		previous[entry.Key] = entry.Bits
+	}
+	entries := make([]generatedsecretv1.EntropyStatus, 0, len(current))
+	for key, bits := range current {
+		entry := generatedsecretv1.EntropyStatus{Key: key, Bits: int32(bits)}
+		if recordedBits, found := previous[key]; found && (!regenerated || recordedBits < entry.Bits) {
+			entry.Bits = recordedBits
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
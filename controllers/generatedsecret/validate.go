@@ -3,69 +3,56 @@ package generatedsecret
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
-	"github.com/containerinfra/kube-secrets-operator/pkg/utils"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
-	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 func (r *GeneratedSecretReconciler) validateSpec(o generatedsecretv1.GeneratedSecret) error {
-	if len(o.Spec.Metadata.Namespaces) == 0 {
+	if len(o.GetTargetNamespaces()) == 0 {
 		// recorder.Event(o, corev1.EventTypeWarning, "Validation failed", "Missing namespaces. Must be > 0")
 		return fmt.Errorf("missing namespaces")
 	}
 	return nil
 }
 
-// getExpectedSecretKeys returns the list of keys that should exist in the secret data
-func getExpectedSecretKeys(generatedSecret generatedsecretv1.GeneratedSecret) []string {
+// getExpectedSecretKeys returns the list of keys that should exist in the secret data in the given namespace
+func getExpectedSecretKeys(generatedSecret generatedsecretv1.GeneratedSecret, namespace string) []string {
 	keys := []string{}
 	for key := range generatedSecret.Spec.Template.Data {
 		keys = append(keys, key)
 	}
+	for key := range generatedSecret.GetTargetOverrides(namespace) {
+		if _, shared := generatedSecret.Spec.Template.Data[key]; !shared {
+			keys = append(keys, key)
+		}
+	}
 	return keys
 }
 
-// ReconcileToSpec manages the lifecycle of secrets after creation and before deletion
-// It will patch secrets with new metadata, and if necessary, will rotate the secrets to new values
-func (r *GeneratedSecretReconciler) reconcileToSpec(ctx context.Context, generatedSecret generatedsecretv1.GeneratedSecret) bool {
+// reconcileToSpec manages the lifecycle of secrets after creation and before deletion
+// It will patch the valid secrets with new metadata and returns the secrets that can still be used as the source for missing targets
+func (r *GeneratedSecretReconciler) reconcileToSpec(ctx context.Context, generatedSecret *generatedsecretv1.GeneratedSecret, validSecrets []corev1.Secret) []corev1.Secret {
 	logger := log.FromContext(ctx)
 
-	updated := false
-	generatedSecretsRefs := []generatedsecretv1.GeneratedSecretRef{}
-
-	for _, secretRef := range generatedSecret.Status.SecretsGeneratedRef.Secrets {
-		secret := &corev1.Secret{}
-		err := r.Client.Get(ctx, types.NamespacedName{
-			Name:      secretRef.Name,
-			Namespace: secretRef.Namespace,
-		}, secret)
-
-		if err != nil {
-			if errors.IsNotFound(err) {
-				logger.Info(fmt.Sprintf("A managed resource is deleted: %s/%s @ %s", secretRef.Namespace, secretRef.Name, secretRef.UID))
-				continue
-			}
-
-			logger.Info(fmt.Sprintf("failed to fetch secret reference: %s", err.Error()))
-			generatedSecretsRefs = append(generatedSecretsRefs, secretRef)
-			continue
-		}
+	reconciledSecrets := []corev1.Secret{}
+	for i := range validSecrets {
+		secret := validSecrets[i].DeepCopy()
 
 		// Check if secret data is missing or empty
 		if len(secret.Data) == 0 {
 			logger.Info("secret data is empty, marking for regeneration", "secret", secret.GetName(), "namespace", secret.GetNamespace())
-			// Remove this secret from refs so it will be recreated in createMissingPasswordSecrets
 			continue
 		}
 
 		// Verify that the secret has all expected data keys from the template
-		expectedKeys := getExpectedSecretKeys(generatedSecret)
+		expectedKeys := getExpectedSecretKeys(*generatedSecret, secret.GetNamespace())
 		missingKeys := []string{}
 		for _, key := range expectedKeys {
 			if _, exists := secret.Data[key]; !exists {
@@ -75,23 +62,16 @@ func (r *GeneratedSecretReconciler) reconcileToSpec(ctx context.Context, generat
 
 		if len(missingKeys) > 0 {
 			logger.Info("secret is missing expected data keys, marking for regeneration", "secret", secret.GetName(), "namespace", secret.GetNamespace(), "missingKeys", missingKeys)
-			// Remove this secret from refs so it will be recreated
 			continue
 		}
 
 		// Prepare expected labels and annotations with ownership labels
-		expectedLabels := generatedSecret.GetSecretLabels()
-		if expectedLabels == nil {
-			expectedLabels = make(map[string]string)
-		}
-		for k, v := range getLabelsForSecret(generatedSecret) {
+		expectedLabels := generatedSecret.GetTargetSecretLabels(secret.GetNamespace())
+		for k, v := range getLabelsForSecret(*generatedSecret) {
 			expectedLabels[k] = v
 		}
 
-		expectedAnnotations := generatedSecret.GetSecretAnnotations()
-		if expectedAnnotations == nil {
-			expectedAnnotations = make(map[string]string)
-		}
+		expectedAnnotations := generatedSecret.GetTargetSecretAnnotations(secret.GetNamespace())
 
 		labelsEqual := equality.Semantic.DeepEqual(secret.GetLabels(), expectedLabels)
 		annotationsEqual := equality.Semantic.DeepEqual(secret.GetAnnotations(), expectedAnnotations)
@@ -100,96 +80,113 @@ func (r *GeneratedSecretReconciler) reconcileToSpec(ctx context.Context, generat
 			logger.Info("secret labels or annotations do not match. Updating secret", "secret", secret.GetName(), "labelsEqual", labelsEqual, "annotationsEqual", annotationsEqual)
 			secret.SetLabels(expectedLabels)
 			secret.SetAnnotations(expectedAnnotations)
-			err := r.Client.Update(ctx, secret)
-			if err != nil {
-				generatedSecretsRefs = append(generatedSecretsRefs, secretRef)
+			// The update response carries the new ResourceVersion, so the secret can be used as-is afterwards
+			if err := r.Client.Update(ctx, secret); err != nil {
 				logger.Info(fmt.Sprintf("Failed to reconcile a secret due to k8s api error: %s", err.Error()))
+				setTargetFailed(&generatedSecret.Status, secret.GetNamespace(), secret.GetName(), err)
+				reconciledSecrets = append(reconciledSecrets, validSecrets[i])
 				continue
 			}
-
-			// Fetch the updated secret to get the latest UID and ResourceVersion
-			updatedSecret := &corev1.Secret{}
-			err = r.Client.Get(ctx, types.NamespacedName{
-				Name:      secret.Name,
-				Namespace: secret.Namespace,
-			}, updatedSecret)
-			if err != nil {
-				logger.Error(err, "failed to fetch updated secret", "secret", secret.GetName())
-				// Use the existing secretRef if we can't fetch the updated one
-				generatedSecretsRefs = append(generatedSecretsRefs, secretRef)
-				continue
-			}
-
-			newSecretRef := utils.GetGeneratedSecretRef(*updatedSecret)
-			generatedSecretsRefs = append(generatedSecretsRefs, newSecretRef)
-
-			updated = true
-		} else {
-			// Even when not updating, use the current secret's metadata to ensure UID/ResourceVersion are up to date
-			currentSecretRef := utils.GetGeneratedSecretRef(*secret)
-			generatedSecretsRefs = append(generatedSecretsRefs, currentSecretRef)
+			setTargetReady(&generatedSecret.Status, secret.GetNamespace(), secret.GetName(), true)
 		}
+		reconciledSecrets = append(reconciledSecrets, *secret)
 	}
+	return reconciledSecrets
+}
 
-	if !equality.Semantic.DeepEqual(generatedSecret.Status.SecretsGeneratedRef.Secrets, generatedSecretsRefs) {
-		generatedSecret.Status.SecretsGeneratedRef.Secrets = generatedSecretsRefs
-		err := r.updateStatusOrRetry(ctx, &generatedSecret)
-		if err != nil {
-			logger.Error(err, "failed to reconcile generated secret")
-			return false
-		}
+// secretTargets describes the state of all target secrets of a GeneratedSecret
+type secretTargets struct {
+	// valid secrets match the reference in the status
+	valid []corev1.Secret
+	// invalid secrets have been modified outside of the operator since they were referenced
+	invalid []corev1.Secret
+	// missing lists the target namespaces without a valid or invalid secret
+	missing []string
+	// stale secrets are managed by the GeneratedSecret, but no longer belong to one of its targets
+	stale []corev1.Secret
+}
+
+// listManagedSecrets lists all secrets that are labeled as managed by the GeneratedSecret, across all namespaces
+func (r *GeneratedSecretReconciler) listManagedSecrets(ctx context.Context, generatedSecret generatedsecretv1.GeneratedSecret) ([]corev1.Secret, error) {
+	secrets := &corev1.SecretList{}
+	if err := r.Client.List(ctx, secrets, client.MatchingLabels(getLabelsForSecret(generatedSecret))); err != nil {
+		return nil, fmt.Errorf("failed to list managed secrets: %w", err)
 	}
+	return secrets.Items, nil
+}
 
-	return updated
+// wantedSecrets returns the namespace and name of the secret of every target
+func wantedSecrets(o generatedsecretv1.GeneratedSecret) map[types.NamespacedName]bool {
+	wanted := map[types.NamespacedName]bool{}
+	for _, namespace := range o.GetTargetNamespaces() {
+		wanted[types.NamespacedName{Namespace: namespace, Name: o.GetTargetSecretName(namespace)}] = true
+	}
+	return wanted
 }
 
-// FetchExistingSecrets returns a list of existing password secrets in the cluster
-func (r *GeneratedSecretReconciler) fetchExistingSecrets(ctx context.Context, o generatedsecretv1.GeneratedSecret) ([]corev1.Secret, []corev1.Secret) {
+// fetchExistingSecrets determines the state of every target by comparing the status references and the spec
+// against the snapshot of managed secrets
+func (r *GeneratedSecretReconciler) fetchExistingSecrets(ctx context.Context, o generatedsecretv1.GeneratedSecret, managedSecrets []corev1.Secret) secretTargets {
 	logger := log.FromContext(ctx)
 
-	invalidSecrets := []corev1.Secret{}
-	validSecrets := []corev1.Secret{}
-
-	for _, secretRef := range o.Status.SecretsGeneratedRef.Secrets {
-		secret := &corev1.Secret{}
+	targets := secretTargets{
+		valid:   []corev1.Secret{},
+		invalid: []corev1.Secret{},
+		missing: []string{},
+		stale:   []corev1.Secret{},
+	}
 
-		err := r.Client.Get(ctx, types.NamespacedName{
-			Name:      secretRef.Name,
-			Namespace: secretRef.Namespace,
-		}, secret)
+	secretsByKey := indexSecrets(managedSecrets)
+	wanted := wantedSecrets(o)
+	for _, secret := range managedSecrets {
+		if !wanted[types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}] {
+			targets.stale = append(targets.stale, secret)
+		}
+	}
 
-		if err != nil {
-			// TODO: handle more errors
-			if errors.IsNotFound(err) {
-				logger.Info(fmt.Sprintf("a managed resource is deleted: %s/%s @ %s", secretRef.Namespace, secretRef.Name, secretRef.UID))
-				continue
-			}
-			logger.Error(err, "Secret get errored")
-			// validSecrets = append(validSecrets, *secret)
+	found := map[types.NamespacedName]bool{}
+	for _, secretRef := range o.Status.SecretsGeneratedRef.Secrets {
+		key := types.NamespacedName{Namespace: secretRef.Namespace, Name: secretRef.Name}
+		managedSecret, exists := secretsByKey[key]
+		if !exists {
+			logger.Info(fmt.Sprintf("a managed resource is deleted: %s/%s @ %s", secretRef.Namespace, secretRef.Name, secretRef.UID))
+			continue
+		}
+		if !wanted[key] {
+			// The secret is no longer part of the spec, it is reported as stale
 			continue
 		}
+		found[key] = true
+		secret := *managedSecret
 
 		if secret.UID != secretRef.UID {
 			logger.Info(fmt.Sprintf("Secret UID invalid. Found: %s, expected: %s", secret.UID, secretRef.UID))
 			// TODO Make as invalid reference, possibly resync?
-			invalidSecrets = append(invalidSecrets, *secret)
+			targets.invalid = append(targets.invalid, secret)
 			continue
 		}
 		if secret.GetResourceVersion() != secretRef.ResourceVersion {
 			logger.Info(fmt.Sprintf("Secret ResourceVersion invalid. Found: %s, expected: %s", secret.GetResourceVersion(), secretRef.ResourceVersion))
 			// TODO Make as invalid reference, possibly resync?
 			// Someone probably manually edited, or this resource got modified by an external system
-			invalidSecrets = append(invalidSecrets, *secret)
+			targets.invalid = append(targets.invalid, secret)
 			continue
 		}
 
 		if secret.Type != corev1.SecretType(secretRef.Type) {
 			logger.Info(fmt.Sprintf("Secret Type invalid. Found: %s, expected: %s", secret.Type, secretRef.Type))
 			// Should never happen, this means we got a bug in our code or someone manually edited various resources; this should however update the resource version
-			invalidSecrets = append(invalidSecrets, *secret)
+			targets.invalid = append(targets.invalid, secret)
 			continue
 		}
-		validSecrets = append(validSecrets, *secret)
+		targets.valid = append(targets.valid, secret)
+	}
+
+	for key := range wanted {
+		if !found[key] {
+			targets.missing = append(targets.missing, key.Namespace)
+		}
 	}
-	return validSecrets, invalidSecrets
+	sort.Strings(targets.missing)
+	return targets
 }
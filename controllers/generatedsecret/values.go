@@ -3,6 +3,8 @@ package generatedsecret
 import (
 	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 )
 
 const (
@@ -11,6 +13,16 @@ const (
 	LabelGeneratedSecretRef       = "generatedsecret.containerinfra.io/ref"
 )
 
+// ManagedSecretSelector selects all secrets that are managed by a GeneratedSecret.
+// It is used to restrict the secret informer cache to operator managed secrets.
+func ManagedSecretSelector() labels.Selector {
+	requirement, err := labels.NewRequirement(LabelGeneratedSecretName, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+	return labels.NewSelector().Add(*requirement)
+}
+
 func getLabelsForSecret(generatedSecret generatedsecretv1.GeneratedSecret) map[string]string {
 	return map[string]string{
 		LabelGeneratedSecretName:      generatedSecret.Name,
@@ -0,0 +1,63 @@
+package generatedsecret
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultMaxConcurrentWrites is the number of secrets written in parallel when no limit is configured
+const DefaultMaxConcurrentWrites = 10
+
+// secretWriteFunc writes a single target secret. It may update the secret in place, e.g. with the response of the API server.
+type secretWriteFunc func(ctx context.Context, secret *corev1.Secret) error
+
+// writeSecrets runs write for every secret using a bounded number of workers. A failing write does not affect the
+// other targets, the error of each write is returned at the index of its secret so results can be aggregated afterwards.
+// The write function must not modify shared state, such as the GeneratedSecret status.
+func (r *GeneratedSecretReconciler) writeSecrets(ctx context.Context, secrets []corev1.Secret, write secretWriteFunc) []error {
+	errs := make([]error, len(secrets))
+
+	concurrency := r.MaxConcurrentWrites
+	if concurrency <= 0 {
+		concurrency = DefaultMaxConcurrentWrites
+	}
+
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for i := range secrets {
+		group.Go(func() error {
+			errs[i] = write(ctx, &secrets[i])
+			return nil
+		})
+	}
+	_ = group.Wait()
+	return errs
+}
+
+// secretIndex returns the index of every secret, so write functions can record a result per secret without locking
+func secretIndex(secrets []corev1.Secret) map[*corev1.Secret]int {
+	index := make(map[*corev1.Secret]int, len(secrets))
+	for i := range secrets {
+		index[&secrets[i]] = i
+	}
+	return index
+}
+
+// skipFailed wraps write, so secrets that already failed, e.g. because their values could not be rendered, are not
+// written and report their error instead. The errors are given at the index of the secret they belong to.
+func skipFailed(secrets []corev1.Secret, errs []error, write secretWriteFunc) secretWriteFunc {
+	failed := map[*corev1.Secret]error{}
+	for i := range secrets {
+		if errs[i] != nil {
+			failed[&secrets[i]] = errs[i]
+		}
+	}
+	return func(ctx context.Context, secret *corev1.Secret) error {
+		if err, found := failed[secret]; found {
+			return err
+		}
+		return write(ctx, secret)
+	}
+}
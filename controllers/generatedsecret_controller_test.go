@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	generatedsecretv1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+)
+
+const (
+	timeout  = 10 * time.Second
+	interval = 250 * time.Millisecond
+)
+
+// createNamespace creates a namespace with a unique name, so every test starts from an empty namespace
+func createNamespace() string {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "generatedsecret-"}}
+	Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+	return namespace.Name
+}
+
+// getSecret returns the secret with the given namespace and name, for use in Eventually
+func getSecret(g Gomega, namespace, name string) *corev1.Secret {
+	secret := &corev1.Secret{}
+	g.Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)).To(Succeed())
+	return secret
+}
+
+// updateGeneratedSecret applies mutate to the latest version of the GeneratedSecret
+func updateGeneratedSecret(generatedSecret *generatedsecretv1.GeneratedSecret, mutate func(*generatedsecretv1.GeneratedSecret)) {
+	Expect(retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(generatedSecret), generatedSecret); err != nil {
+			return err
+		}
+		mutate(generatedSecret)
+		return k8sClient.Update(ctx, generatedSecret)
+	})).To(Succeed())
+}
+
+var _ = Describe("GeneratedSecret controller", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = createNamespace()
+	})
+
+	It("reports a failed target without affecting the other targets", func() {
+		ready, conflicting := createNamespace(), createNamespace()
+		// A secret that is not managed by the operator already uses the name of the target secret
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-credentials", Namespace: conflicting},
+			StringData: map[string]string{"password": "manual"},
+		})).To(Succeed())
+
+		generatedSecret := &generatedsecretv1.GeneratedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "partial", Namespace: namespace},
+			Spec: generatedsecretv1.GeneratedSecretSpec{
+				Metadata: generatedsecretv1.SecretMetadata{Name: "app-credentials", Namespaces: []string{ready, conflicting}},
+				Template: generatedsecretv1.SecretTemplate{Data: generatedsecretv1.SecretValueItems{
+					"password": {Generated: &generatedsecretv1.GeneratedValueSpec{Length: 16}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, generatedSecret)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(generatedSecret), generatedSecret)).To(Succeed())
+			g.Expect(generatedSecret.Status.Targets).To(ConsistOf(
+				And(HaveField("Namespace", ready), HaveField("State", generatedsecretv1.TargetStateReady)),
+				And(HaveField("Namespace", conflicting), HaveField("State", generatedsecretv1.TargetStateFailed), HaveField("LastError", ContainSubstring("not managed"))),
+			))
+			g.Expect(generatedSecret.Status.ReadyTargets).To(Equal(1))
+			g.Expect(generatedSecret.Status.FailedTargets).To(Equal(1))
+			condition := meta.FindStatusCondition(generatedSecret.Status.Conditions, generatedsecretv1.ConditionReady)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		}, timeout, interval).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(getSecret(g, ready, "app-credentials").Data).To(HaveKey("password"))
+		}, timeout, interval).Should(Succeed())
+		Expect(getSecret(Default, conflicting, "app-credentials").Data).To(HaveKeyWithValue("password", []byte("manual")))
+	})
+
+	It("creates and prunes target objects when targets are tracked in objects", func() {
+		first, second := createNamespace(), createNamespace()
+		generatedSecret := &generatedsecretv1.GeneratedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "objects", Namespace: namespace},
+			Spec: generatedsecretv1.GeneratedSecretSpec{
+				TargetTracking: generatedsecretv1.TrackTargetsInObjects,
+				Metadata:       generatedsecretv1.SecretMetadata{Name: "app-credentials", Namespaces: []string{first, second}},
+				Template: generatedsecretv1.SecretTemplate{Data: generatedsecretv1.SecretValueItems{
+					"password": {Generated: &generatedsecretv1.GeneratedValueSpec{Length: 16}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, generatedSecret)).To(Succeed())
+
+		targetNamespaces := func(g Gomega) []string {
+			targets := &generatedsecretv1.GeneratedSecretTargetList{}
+			g.Expect(k8sClient.List(ctx, targets, client.InNamespace(namespace))).To(Succeed())
+			namespaces := []string{}
+			for _, target := range targets.Items {
+				g.Expect(target.Spec.GeneratedSecretName).To(Equal(generatedSecret.Name))
+				g.Expect(target.Status.State).To(Equal(generatedsecretv1.TargetStateReady))
+				g.Expect(target.Status.SecretRef).NotTo(BeNil())
+				namespaces = append(namespaces, target.Spec.Namespace)
+			}
+			return namespaces
+		}
+		Eventually(targetNamespaces, timeout, interval).Should(ConsistOf(first, second))
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(generatedSecret), generatedSecret)).To(Succeed())
+			g.Expect(generatedSecret.Status.ReadyTargets).To(Equal(2))
+			g.Expect(generatedSecret.Status.Targets).To(BeEmpty())
+			g.Expect(generatedSecret.Status.SecretsGeneratedRef.Secrets).To(BeEmpty())
+		}, timeout, interval).Should(Succeed())
+
+		updateGeneratedSecret(generatedSecret, func(generatedSecret *generatedsecretv1.GeneratedSecret) {
+			generatedSecret.Spec.Metadata.Namespaces = []string{first}
+		})
+		Eventually(targetNamespaces, timeout, interval).Should(ConsistOf(first))
+	})
+
+	It("renders a template again when one of its inputs changes", func() {
+		target := createNamespace()
+		input := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-input", Namespace: namespace},
+			StringData: map[string]string{"host": "db-1"},
+		}
+		Expect(k8sClient.Create(ctx, input)).To(Succeed())
+		config := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-config", Namespace: namespace},
+			Data:       map[string]string{"port": "5432"},
+		}
+		Expect(k8sClient.Create(ctx, config)).To(Succeed())
+
+		generatedSecret := &generatedsecretv1.GeneratedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "inputs", Namespace: namespace},
+			Spec: generatedsecretv1.GeneratedSecretSpec{
+				Metadata: generatedsecretv1.SecretMetadata{Name: "db-credentials", Namespaces: []string{target}},
+				Template: generatedsecretv1.SecretTemplate{Data: generatedsecretv1.SecretValueItems{
+					"dsn": {Templated: &generatedsecretv1.TemplatedValueSpec{
+						Template:       "postgres://{{ .Ref.host }}:{{ .Inputs.config.port }}",
+						InputSecretRef: &generatedsecretv1.SecretReference{Name: input.Name},
+						Inputs: map[string]generatedsecretv1.TemplateInput{
+							"config": {ConfigMapRef: &generatedsecretv1.ConfigMapReference{Name: config.Name}},
+						},
+					}},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, generatedSecret)).To(Succeed())
+
+		dsn := func(g Gomega) string {
+			return string(getSecret(g, target, "db-credentials").Data["dsn"])
+		}
+		Eventually(dsn, timeout, interval).Should(Equal("postgres://db-1:5432"))
+
+		input.StringData = map[string]string{"host": "db-2"}
+		Expect(k8sClient.Update(ctx, input)).To(Succeed())
+		Eventually(dsn, timeout, interval).Should(Equal("postgres://db-2:5432"))
+
+		config.Data = map[string]string{"port": "6543"}
+		Expect(k8sClient.Update(ctx, config)).To(Succeed())
+		Eventually(dsn, timeout, interval).Should(Equal("postgres://db-2:6543"))
+	})
+
+	It("applies an override to a single namespace only", func() {
+		shared, overridden := createNamespace(), createNamespace()
+		generatedSecret := &generatedsecretv1.GeneratedSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "overrides", Namespace: namespace},
+			Spec: generatedsecretv1.GeneratedSecretSpec{
+				Metadata: generatedsecretv1.SecretMetadata{Name: "app-credentials", Namespaces: []string{shared}},
+				Template: generatedsecretv1.SecretTemplate{Data: generatedsecretv1.SecretValueItems{
+					"username": {Value: "admin"},
+					"password": {Generated: &generatedsecretv1.GeneratedValueSpec{Length: 16}},
+				}},
+				Targets: []generatedsecretv1.TargetSpec{{
+					Namespace: overridden,
+					Labels:    map[string]string{"env": "prod"},
+					Data: generatedsecretv1.SecretValueItems{
+						"username": {Value: "admin-prod"},
+					},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, generatedSecret)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			sharedSecret := getSecret(g, shared, "app-credentials")
+			overriddenSecret := getSecret(g, overridden, "app-credentials")
+			g.Expect(sharedSecret.Data).To(HaveKeyWithValue("username", []byte("admin")))
+			g.Expect(overriddenSecret.Data).To(HaveKeyWithValue("username", []byte("admin-prod")))
+			g.Expect(overriddenSecret.Data["password"]).To(Equal(sharedSecret.Data["password"]))
+			g.Expect(sharedSecret.Labels).NotTo(HaveKey("env"))
+			g.Expect(overriddenSecret.Labels).To(HaveKeyWithValue("env", "prod"))
+		}, timeout, interval).Should(Succeed())
+	})
+})
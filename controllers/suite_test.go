@@ -18,10 +18,13 @@ package controllers
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -47,6 +50,9 @@ var ctx context.Context
 var cancel context.CancelFunc
 
 func TestAPIs(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set, run the controller tests with make test")
+	}
 	RegisterFailHandler(Fail)
 
 	RunSpecs(t, "Controller Suite")
@@ -78,14 +84,32 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	// The caches are set up as by the operator, so only managed secrets are cached and template inputs are watched
 	k8sManager, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {Label: generatedsecret.ManagedSecretSelector()},
+			},
+		},
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	inputCache, err := cache.New(k8sManager.GetConfig(), cache.Options{
+		HTTPClient:       k8sManager.GetHTTPClient(),
+		Scheme:           k8sManager.GetScheme(),
+		Mapper:           k8sManager.GetRESTMapper(),
+		DefaultTransform: generatedsecret.TransformInputMetadata,
 	})
 	Expect(err).ToNot(HaveOccurred())
+	Expect(k8sManager.Add(inputCache)).To(Succeed())
 
 	err = (&generatedsecret.GeneratedSecretReconciler{
-		Client: k8sManager.GetClient(),
-		Scheme: k8sManager.GetScheme(),
+		Client:     k8sManager.GetClient(),
+		Scheme:     k8sManager.GetScheme(),
+		Recorder:   k8sManager.GetEventRecorderFor("generated-secret-controller"),
+		APIReader:  k8sManager.GetAPIReader(),
+		InputCache: inputCache,
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
@@ -0,0 +1,39 @@
+package dockerconfig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Config is the content of a .dockerconfigjson key
+type Config struct {
+	Auths map[string]Auth `json:"auths"`
+}
+
+// Auth holds the credentials of a single registry
+type Auth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+	// Auth is the base64 encoding of username:password, as used by docker and the kubelet
+	Auth string `json:"auth"`
+}
+
+// Render returns the docker config JSON with the credentials of the registry
+func Render(registry, username, password, email string) ([]byte, error) {
+	if registry == "" {
+		return nil, fmt.Errorf("dockerconfig: registry must not be empty")
+	}
+	config := Config{
+		Auths: map[string]Auth{
+			registry: {
+				Username: username,
+				Password: password,
+				Email:    email,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+			},
+		},
+	}
+	return json.Marshal(config)
+}
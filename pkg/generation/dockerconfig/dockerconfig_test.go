@@ -0,0 +1,29 @@
+package dockerconfig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	rendered, err := Render("registry.example.com:5000", "ci", `p@ss:"word"`, "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"auths":{"registry.example.com:5000":{"username":"ci","password":"p@ss:\"word\"","auth":"Y2k6cEBzczoid29yZCI="}}}`, string(rendered))
+
+	var config Config
+	require.NoError(t, json.Unmarshal(rendered, &config))
+	auth, err := base64.StdEncoding.DecodeString(config.Auths["registry.example.com:5000"].Auth)
+	require.NoError(t, err)
+	assert.Equal(t, `ci:p@ss:"word"`, string(auth))
+
+	withEmail, err := Render("registry.example.com", "ci", "pass", "ci@example.com")
+	require.NoError(t, err)
+	assert.Contains(t, string(withEmail), `"email":"ci@example.com"`)
+
+	_, err = Render("", "ci", "pass", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+// Package entropy draws random bytes and numbers from a source of randomness. The generation packages take the source
+// as an io.Reader, callers pass crypto/rand.Reader and tests can pass a deterministic reader.
+//
+// RSA keys and bcrypt salts are the exception: the standard library always generates RSA keys from crypto/rand, and
+// the bcrypt package reads its salts from crypto/rand itself. Both are secure, but not deterministic in tests.
+package entropy
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Read fills b with random bytes from r
+func Read(r io.Reader, b []byte) error {
+	if _, err := io.ReadFull(r, b); err != nil {
+		return fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return nil
+}
+
+// Int returns a uniform random number in [0, max) drawn from r
+func Int(r io.Reader, max int) (int, error) {
+	if max <= 0 {
+		return 0, errors.New("random number must have a positive upper bound")
+	}
+	n, err := rand.Int(r, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random integer: %w", err)
+	}
+	return int(n.Int64()), nil
+}
@@ -0,0 +1,34 @@
+package entropy
+
+import (
+	"bytes"
+	mathrand "math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntDeterministicReader(t *testing.T) {
+	sequence := func(seed byte) []int {
+		r := mathrand.NewChaCha8([32]byte{seed})
+		numbers := make([]int, 16)
+		for i := range numbers {
+			n, err := Int(r, 1000)
+			require.NoError(t, err)
+			require.True(t, n >= 0 && n < 1000)
+			numbers[i] = n
+		}
+		return numbers
+	}
+
+	assert.Equal(t, sequence(1), sequence(1))
+	assert.NotEqual(t, sequence(1), sequence(2))
+}
+
+func TestReadErrors(t *testing.T) {
+	assert.Error(t, Read(bytes.NewReader([]byte{1, 2}), make([]byte, 4)))
+
+	_, err := Int(bytes.NewReader(nil), 0)
+	assert.Error(t, err)
+}
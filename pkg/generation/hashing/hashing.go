@@ -0,0 +1,250 @@
+package hashing
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/entropy"
+)
+
+// Algorithm is a password hashing algorithm
+type Algorithm string
+
+const (
+	// Bcrypt produces bcrypt hashes in the $2a$ format
+	Bcrypt Algorithm = "bcrypt"
+	// Argon2id produces argon2id hashes in the PHC string format, $argon2id$v=19$m=...,t=...,p=...$salt$hash
+	Argon2id Algorithm = "argon2id"
+	// Scrypt produces scrypt hashes in the PHC string format, $scrypt$ln=...,r=8,p=...$salt$hash
+	Scrypt Algorithm = "scrypt"
+	// ScramSHA256 produces SCRAM-SHA-256 verifiers in the format PostgreSQL stores them,
+	// SCRAM-SHA-256$<iterations>:<salt>$<stored key>:<server key>
+	ScramSHA256 Algorithm = "scram-sha-256"
+)
+
+const (
+	// DefaultBcryptCost is the default cost of bcrypt hashes
+	DefaultBcryptCost = bcrypt.DefaultCost
+	// DefaultArgon2idIterations is the default number of passes of argon2id
+	DefaultArgon2idIterations = 3
+	// DefaultArgon2idMemory is the default memory of argon2id in KiB
+	DefaultArgon2idMemory = 64 * 1024
+	// DefaultArgon2idParallelism is the default number of threads of argon2id
+	DefaultArgon2idParallelism = 4
+	// DefaultScryptCost is the default base 2 logarithm of the CPU/memory cost of scrypt
+	DefaultScryptCost = 15
+	// DefaultScryptParallelism is the default parallelization parameter of scrypt
+	DefaultScryptParallelism = 1
+	// DefaultScramIterations is the default number of iterations of SCRAM-SHA-256, the default of PostgreSQL
+	DefaultScramIterations = 4096
+
+	// MaxBcryptCost is the highest accepted bcrypt cost, a hash takes seconds at this cost
+	MaxBcryptCost = 16
+	// MaxArgon2idIterations is the highest accepted number of passes of argon2id
+	MaxArgon2idIterations = 16
+	// MaxArgon2idMemory is the highest accepted memory of argon2id in KiB, 64 MiB. The memory of a hash is allocated
+	// on every reconcile that verifies or computes it, so it must fit in the memory limit of the operator.
+	MaxArgon2idMemory = 64 * 1024
+	// MaxArgon2idParallelism is the highest number of threads of argon2id
+	MaxArgon2idParallelism = 255
+	// MaxScryptCost is the highest accepted base 2 logarithm of the CPU/memory cost of scrypt, which uses 64 MiB at this cost
+	MaxScryptCost = 16
+	// MaxScryptParallelism is the highest accepted parallelization parameter of scrypt
+	MaxScryptParallelism = 16
+	// MaxScramIterations is the highest accepted number of iterations of SCRAM-SHA-256
+	MaxScramIterations = 1000000
+
+	saltLength   = 16
+	keyLength    = 32
+	scryptBlocks = 8
+)
+
+// Params configures a hash. Parameters that do not apply to the algorithm are ignored, and parameters that are not
+// set use the defaults of the algorithm.
+type Params struct {
+	Algorithm Algorithm
+	// Cost is the bcrypt cost, or the base 2 logarithm of the CPU/memory cost of scrypt
+	Cost int
+	// Iterations is the number of passes of argon2id, or the number of iterations of SCRAM-SHA-256
+	Iterations int
+	// Memory is the memory of argon2id in KiB
+	Memory int
+	// Parallelism is the number of threads of argon2id, or the parallelization parameter of scrypt
+	Parallelism int
+}
+
+// withDefaults returns the params with the defaults of the algorithm applied. Parameters above the limits of the
+// algorithm are rejected, as hashes are computed by the operator on every reconcile and would exhaust its memory or CPU.
+func (p Params) withDefaults() (Params, error) {
+	if p.Algorithm == "" {
+		p.Algorithm = Bcrypt
+	}
+	var err error
+	switch p.Algorithm {
+	case Bcrypt:
+		p.Cost = orDefault(p.Cost, DefaultBcryptCost)
+		err = atMost("bcrypt: cost", p.Cost, MaxBcryptCost)
+	case Argon2id:
+		p.Iterations = orDefault(p.Iterations, DefaultArgon2idIterations)
+		p.Memory = orDefault(p.Memory, DefaultArgon2idMemory)
+		p.Parallelism = orDefault(p.Parallelism, DefaultArgon2idParallelism)
+		err = errors.Join(
+			atMost("argon2id: iterations", p.Iterations, MaxArgon2idIterations),
+			atMost("argon2id: memory", p.Memory, MaxArgon2idMemory),
+			atMost("argon2id: parallelism", p.Parallelism, MaxArgon2idParallelism),
+		)
+	case Scrypt:
+		p.Cost = orDefault(p.Cost, DefaultScryptCost)
+		p.Parallelism = orDefault(p.Parallelism, DefaultScryptParallelism)
+		err = errors.Join(
+			atMost("scrypt: cost", p.Cost, MaxScryptCost),
+			atMost("scrypt: parallelism", p.Parallelism, MaxScryptParallelism),
+		)
+	case ScramSHA256:
+		p.Iterations = orDefault(p.Iterations, DefaultScramIterations)
+		err = atMost("scram-sha-256: iterations", p.Iterations, MaxScramIterations)
+	}
+	return p, err
+}
+
+func atMost(name string, value, limit int) error {
+	if value > limit {
+		return fmt.Errorf("%s must be at most %d", name, limit)
+	}
+	return nil
+}
+
+func orDefault(value, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// Hash hashes the value with a new salt drawn from random
+func Hash(random io.Reader, value []byte, params Params) (string, error) {
+	salt := make([]byte, saltLength)
+	if err := entropy.Read(random, salt); err != nil {
+		return "", err
+	}
+
+	params, err := params.withDefaults()
+	if err != nil {
+		return "", err
+	}
+	switch params.Algorithm {
+	case Bcrypt:
+		hashed, err := bcrypt.GenerateFromPassword(value, params.Cost)
+		if err != nil {
+			return "", fmt.Errorf("bcrypt: %w", err)
+		}
+		return string(hashed), nil
+	case Argon2id:
+		return hashArgon2id(value, salt, params)
+	case Scrypt:
+		return hashScrypt(value, salt, params)
+	case ScramSHA256:
+		return hashScramSHA256(value, salt, params)
+	}
+	return "", fmt.Errorf("unsupported hash algorithm %q", params.Algorithm)
+}
+
+// Verify returns true when the hash was derived from the value with the given params. A hash of the value with other
+// parameters, such as a different cost, does not verify, so it is replaced when the parameters change.
+func Verify(hash string, value []byte, params Params) bool {
+	params, err := params.withDefaults()
+	if err != nil {
+		return false
+	}
+	switch params.Algorithm {
+	case Bcrypt:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost == params.Cost && bcrypt.CompareHashAndPassword([]byte(hash), value) == nil
+	case Argon2id, Scrypt, ScramSHA256:
+		salt, err := parseSalt(hash, params)
+		if err != nil {
+			return false
+		}
+		var expected string
+		switch params.Algorithm {
+		case Argon2id:
+			expected, err = hashArgon2id(value, salt, params)
+		case Scrypt:
+			expected, err = hashScrypt(value, salt, params)
+		case ScramSHA256:
+			expected, err = hashScramSHA256(value, salt, params)
+		}
+		return err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(hash)) == 1
+	}
+	return false
+}
+
+// parseSalt returns the salt of a hash in the format the params produce
+func parseSalt(hash string, params Params) ([]byte, error) {
+	switch params.Algorithm {
+	case Argon2id, Scrypt:
+		// $<algorithm>$...$<salt>$<hash>
+		parts := strings.Split(hash, "$")
+		if len(parts) < 4 || parts[1] != string(params.Algorithm) {
+			return nil, errors.New("invalid hash")
+		}
+		return base64.RawStdEncoding.DecodeString(parts[len(parts)-2])
+	case ScramSHA256:
+		// SCRAM-SHA-256$<iterations>:<salt>$<stored key>:<server key>
+		rest, found := strings.CutPrefix(hash, "SCRAM-SHA-256$")
+		if !found {
+			return nil, errors.New("invalid hash")
+		}
+		_, rest, found = strings.Cut(rest, ":")
+		salt, _, found2 := strings.Cut(rest, "$")
+		if !found || !found2 {
+			return nil, errors.New("invalid hash")
+		}
+		return base64.StdEncoding.DecodeString(salt)
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm %q", params.Algorithm)
+}
+
+func hashArgon2id(value, salt []byte, params Params) (string, error) {
+	key := argon2.IDKey(value, salt, uint32(params.Iterations), uint32(params.Memory), uint8(params.Parallelism), keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func hashScrypt(value, salt []byte, params Params) (string, error) {
+	key, err := scrypt.Key(value, salt, 1<<params.Cost, scryptBlocks, params.Parallelism, keyLength)
+	if err != nil {
+		return "", fmt.Errorf("scrypt: %w", err)
+	}
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", params.Cost, scryptBlocks, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func hashScramSHA256(value, salt []byte, params Params) (string, error) {
+	salted, err := pbkdf2.Key(sha256.New, string(value), salt, params.Iterations, sha256.Size)
+	if err != nil {
+		return "", fmt.Errorf("scram-sha-256: %w", err)
+	}
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := hmacSHA256(salted, "Server Key")
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", params.Iterations, base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(storedKey[:]), base64.StdEncoding.EncodeToString(serverKey)), nil
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,119 @@
+package hashing
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	mathrand "math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		prefix string
+		other  Params
+	}{
+		{
+			name:   "bcrypt",
+			params: Params{Algorithm: Bcrypt, Cost: 4},
+			prefix: "$2a$04$",
+			other:  Params{Algorithm: Bcrypt, Cost: 5},
+		},
+		{
+			name:   "argon2id",
+			params: Params{Algorithm: Argon2id, Memory: 1024, Iterations: 1, Parallelism: 1},
+			prefix: "$argon2id$v=19$m=1024,t=1,p=1$",
+			other:  Params{Algorithm: Argon2id, Memory: 2048, Iterations: 1, Parallelism: 1},
+		},
+		{
+			name:   "scrypt",
+			params: Params{Algorithm: Scrypt, Cost: 10},
+			prefix: "$scrypt$ln=10,r=8,p=1$",
+			other:  Params{Algorithm: Scrypt, Cost: 11},
+		},
+		{
+			name:   "scram-sha-256",
+			params: Params{Algorithm: ScramSHA256, Iterations: 1000},
+			prefix: "SCRAM-SHA-256$1000:",
+			other:  Params{Algorithm: ScramSHA256, Iterations: 2000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := Hash(rand.Reader, []byte("secret"), tt.params)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, tt.prefix), hash)
+
+			assert.True(t, Verify(hash, []byte("secret"), tt.params))
+			assert.False(t, Verify(hash, []byte("other"), tt.params), "a hash of another value must not verify")
+			assert.False(t, Verify(hash, []byte("secret"), tt.other), "a hash with other parameters must not verify")
+			assert.False(t, Verify("invalid", []byte("secret"), tt.params))
+
+			again, err := Hash(rand.Reader, []byte("secret"), tt.params)
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, again, "every hash uses a new salt")
+		})
+	}
+}
+
+func TestHashDefaults(t *testing.T) {
+	hash, err := Hash(rand.Reader, []byte("secret"), Params{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$10$"), hash)
+	assert.True(t, Verify(hash, []byte("secret"), Params{Algorithm: Bcrypt, Cost: DefaultBcryptCost}))
+}
+
+func TestHashLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		err    string
+	}{
+		{name: "bcrypt cost", params: Params{Algorithm: Bcrypt, Cost: 17}, err: "bcrypt: cost must be at most 16"},
+		{name: "argon2id memory", params: Params{Algorithm: Argon2id, Memory: 2147483647}, err: "argon2id: memory must be at most 65536"},
+		{name: "argon2id iterations", params: Params{Algorithm: Argon2id, Iterations: 17}, err: "argon2id: iterations must be at most 16"},
+		{name: "argon2id parallelism", params: Params{Algorithm: Argon2id, Parallelism: 256}, err: "argon2id: parallelism must be at most 255"},
+		{name: "scrypt cost", params: Params{Algorithm: Scrypt, Cost: 30}, err: "scrypt: cost must be at most 16"},
+		{name: "scrypt parallelism", params: Params{Algorithm: Scrypt, Parallelism: 17}, err: "scrypt: parallelism must be at most 16"},
+		{name: "scram-sha-256 iterations", params: Params{Algorithm: ScramSHA256, Iterations: 1000001}, err: "scram-sha-256: iterations must be at most 1000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Hash(rand.Reader, []byte("secret"), tt.params)
+			assert.ErrorContains(t, err, tt.err)
+			assert.False(t, Verify("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$aGFzaA", []byte("secret"), tt.params))
+		})
+	}
+}
+
+func TestScramSHA256(t *testing.T) {
+	// The verifier of the example exchange of RFC 7677, for the password "pencil"
+	salt, err := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	require.NoError(t, err)
+	hash, err := hashScramSHA256([]byte("pencil"), salt, Params{Algorithm: ScramSHA256, Iterations: 4096})
+	require.NoError(t, err)
+	assert.Equal(t, "SCRAM-SHA-256$4096:W22ZaJ0SNY7soEsUEjb6gQ==$WG5d8oPm3OtcPnkdi4Uo7BkeZkBFzpcXkuLmtbsT4qY=:wfPLwcE6nTWhTAmQ7tl2KeoiWGPlZqQxSrmfPwDl2dU=", hash)
+	assert.True(t, Verify(hash, []byte("pencil"), Params{Algorithm: ScramSHA256}))
+}
+
+func TestHashSaltFromEntropySource(t *testing.T) {
+	hash := func() string {
+		hashed, err := Hash(mathrand.NewChaCha8([32]byte{7}), []byte("secret"), Params{Algorithm: ScramSHA256})
+		require.NoError(t, err)
+		return hashed
+	}
+	assert.Equal(t, hash(), hash())
+}
+
+func TestHashUnsupportedAlgorithm(t *testing.T) {
+	_, err := Hash(rand.Reader, []byte("secret"), Params{Algorithm: "md5"})
+	require.Error(t, err)
+	assert.False(t, Verify("secret", []byte("secret"), Params{Algorithm: "md5"}))
+}
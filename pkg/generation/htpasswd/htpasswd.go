@@ -0,0 +1,70 @@
+package htpasswd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/hashing"
+)
+
+// Users returns the usernames of an htpasswd file, in the order they appear in the file
+func Users(file []byte) []string {
+	users := []string{}
+	for _, line := range strings.Split(string(file), "\n") {
+		user, _, found := strings.Cut(strings.TrimSpace(line), ":")
+		if found && user != "" {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// hashes returns the hash of every user of an htpasswd file
+func hashes(file []byte) map[string]string {
+	hashes := map[string]string{}
+	for _, line := range strings.Split(string(file), "\n") {
+		user, hash, found := strings.Cut(strings.TrimSpace(line), ":")
+		if found && user != "" {
+			hashes[user] = hash
+		}
+	}
+	return hashes
+}
+
+// ValidateUser returns an error when the username can not be used in an htpasswd file
+func ValidateUser(user string) error {
+	if user == "" || strings.ContainsAny(user, ":\n\r") || strings.TrimSpace(user) != user {
+		return fmt.Errorf("htpasswd: %q is not a valid username", user)
+	}
+	return nil
+}
+
+// Render returns an htpasswd file with a bcrypt hash of the password of every user, in the order of the users.
+// The hash of a user in the existing file is kept when it still matches the password and the cost, so the file only
+// changes when a user or password does.
+func Render(users []string, passwords map[string][]byte, existing []byte, cost int) ([]byte, error) {
+	params := hashing.Params{Algorithm: hashing.Bcrypt, Cost: cost}
+	current := hashes(existing)
+
+	var out bytes.Buffer
+	for _, user := range users {
+		if err := ValidateUser(user); err != nil {
+			return nil, err
+		}
+		password, found := passwords[user]
+		if !found {
+			return nil, fmt.Errorf("htpasswd: missing password of user %s", user)
+		}
+		hash, found := current[user]
+		if !found || !hashing.Verify(hash, password, params) {
+			var err error
+			if hash, err = hashing.Hash(rand.Reader, password, params); err != nil {
+				return nil, fmt.Errorf("htpasswd: %w", err)
+			}
+		}
+		fmt.Fprintf(&out, "%s:%s\n", user, hash)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package htpasswd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRender(t *testing.T) {
+	passwords := map[string][]byte{
+		"admin": []byte("admin-pass"),
+		"user":  []byte("user-pass"),
+	}
+
+	file, err := Render([]string{"admin", "user"}, passwords, nil, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "user"}, Users(file))
+	lines := strings.Split(strings.TrimSuffix(string(file), "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, user := range []string{"admin", "user"} {
+		hash, found := strings.CutPrefix(lines[i], user+":")
+		require.True(t, found)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), passwords[user]))
+	}
+
+	t.Run("hashes are kept while they match", func(t *testing.T) {
+		again, err := Render([]string{"admin", "user"}, passwords, file, 4)
+		require.NoError(t, err)
+		assert.Equal(t, string(file), string(again))
+	})
+
+	t.Run("only the hash of a changed password is replaced", func(t *testing.T) {
+		changed := map[string][]byte{"admin": []byte("admin-pass"), "user": []byte("changed")}
+		again, err := Render([]string{"admin", "user"}, changed, file, 4)
+		require.NoError(t, err)
+		againLines := strings.Split(string(again), "\n")
+		assert.Equal(t, lines[0], againLines[0])
+		assert.NotEqual(t, lines[1], againLines[1])
+	})
+
+	t.Run("hashes are replaced when the cost changes", func(t *testing.T) {
+		again, err := Render([]string{"admin"}, passwords, file, 5)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(again), "admin:$2a$05$"))
+	})
+
+	t.Run("users are written in the given order", func(t *testing.T) {
+		again, err := Render([]string{"user", "admin"}, passwords, file, 4)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user", "admin"}, Users(again))
+	})
+
+	t.Run("a missing password is an error", func(t *testing.T) {
+		_, err := Render([]string{"other"}, passwords, nil, 4)
+		require.Error(t, err)
+	})
+
+	t.Run("an invalid username is an error", func(t *testing.T) {
+		_, err := Render([]string{"ad:min"}, map[string][]byte{"ad:min": []byte("pass")}, nil, 4)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,102 @@
+// Package passphrase generates diceware passphrases of random words from the EFF large wordlist, see
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases. The wordlist is embedded in the binary.
+package passphrase
+
+import (
+	_ "embed"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/entropy"
+)
+
+// DefaultWords is the number of words of a passphrase unless set, about 77 bits of entropy
+const DefaultWords = 6
+
+// DefaultSeparator separates the words of a passphrase unless set
+const DefaultSeparator = "-"
+
+// effLargeWordlist is the EFF large wordlist, published by the Electronic Frontier Foundation under CC BY 3.0 US.
+// Every line holds the five dice rolls of a word and the word, separated by a tab.
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+// Words returns the words of the EFF large wordlist, in the order of their dice rolls
+var Words = sync.OnceValue(func() []string {
+	words := make([]string, 0, 7776)
+	for _, line := range strings.Split(strings.TrimSpace(effLargeWordlist), "\n") {
+		_, word, _ := strings.Cut(line, "\t")
+		words = append(words, word)
+	}
+	return words
+})
+
+// Options configures a passphrase
+type Options struct {
+	// Words is the number of words, defaults to DefaultWords
+	Words int
+	// Separator is placed between the words
+	Separator string
+	// Capitalize capitalizes the first letter of every word
+	Capitalize bool
+	// Digit appends a random digit to a random word
+	Digit bool
+}
+
+// Generate returns a passphrase of words drawn from random
+func Generate(random io.Reader, opts Options) (string, error) {
+	if opts.Words <= 0 {
+		opts.Words = DefaultWords
+	}
+	words := Words()
+	if len(words) == 0 {
+		return "", errors.New("passphrase: the wordlist is empty")
+	}
+
+	passphrase := make([]string, opts.Words)
+	for i := range passphrase {
+		index, err := entropy.Int(random, len(words))
+		if err != nil {
+			return "", err
+		}
+		passphrase[i] = words[index]
+		if opts.Capitalize {
+			first, size := utf8.DecodeRuneInString(passphrase[i])
+			passphrase[i] = string(unicode.ToUpper(first)) + passphrase[i][size:]
+		}
+	}
+	if opts.Digit {
+		word, err := entropy.Int(random, len(passphrase))
+		if err != nil {
+			return "", err
+		}
+		digit, err := entropy.Int(random, 10)
+		if err != nil {
+			return "", err
+		}
+		passphrase[word] += strconv.Itoa(digit)
+	}
+	return strings.Join(passphrase, opts.Separator), nil
+}
+
+// EntropyBits returns the entropy in bits of a passphrase, about 12.9 bits per word. The choice of the word the
+// digit is appended to is not counted, so it is a lower bound as long as the words can be told apart, by a separator
+// that is not part of any word or by capitalization. Four words hold a hyphen, e.g. t-shirt, so a few passphrases
+// separated by hyphens read as other words too, which costs a negligible fraction of a bit.
+func EntropyBits(opts Options) float64 {
+	if opts.Words <= 0 {
+		opts.Words = DefaultWords
+	}
+	bits := float64(opts.Words) * math.Log2(float64(len(Words())))
+	if opts.Digit {
+		bits += math.Log2(10)
+	}
+	return bits
+}
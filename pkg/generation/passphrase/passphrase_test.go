@@ -0,0 +1,74 @@
+package passphrase
+
+import (
+	"crypto/rand"
+	mathrand "math/rand/v2"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWords(t *testing.T) {
+	words := Words()
+	require.Len(t, words, 7776)
+	assert.Equal(t, "abacus", words[0])
+	assert.Equal(t, "zoom", words[len(words)-1])
+
+	unique := slices.Compact(slices.Sorted(slices.Values(words)))
+	assert.Len(t, unique, len(words))
+	for _, word := range words {
+		assert.Regexp(t, `^[a-z-]+$`, word)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		pattern string
+		words   int
+	}{
+		{name: "defaults", opts: Options{Separator: DefaultSeparator}, pattern: `^[a-z-]+$`},
+		{name: "separator", opts: Options{Words: 4, Separator: " "}, pattern: `^[a-z-]+( [a-z-]+){3}$`, words: 4},
+		{name: "dot separator", opts: Options{Words: 5, Separator: "."}, pattern: `^([a-z-]+\.){4}[a-z-]+$`, words: 5},
+		{name: "capitalized", opts: Options{Words: 3, Separator: "", Capitalize: true}, pattern: `^([A-Z][a-z-]*){3}$`},
+		{name: "digit", opts: Options{Words: 4, Separator: " ", Digit: true}, pattern: `^[a-z-]+[0-9]?( [a-z-]+[0-9]?){3}$`, words: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := Generate(rand.Reader, tt.opts)
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(tt.pattern), value)
+			if tt.opts.Digit {
+				assert.Len(t, regexp.MustCompile(`[0-9]`).FindAllString(value, -1), 1)
+			}
+			if tt.words > 0 && tt.opts.Separator != "" {
+				parts := strings.Split(value, tt.opts.Separator)
+				assert.Len(t, parts, tt.words)
+				for _, part := range parts {
+					assert.Contains(t, Words(), strings.TrimRight(part, "0123456789"))
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateDeterministicReader(t *testing.T) {
+	generate := func() string {
+		value, err := Generate(mathrand.NewChaCha8([32]byte{7}), Options{Separator: DefaultSeparator, Capitalize: true, Digit: true})
+		require.NoError(t, err)
+		return value
+	}
+	assert.Equal(t, generate(), generate())
+}
+
+func TestEntropyBits(t *testing.T) {
+	assert.InDelta(t, 77.5, EntropyBits(Options{}), 0.1)
+	assert.InDelta(t, 51.7, EntropyBits(Options{Words: 4}), 0.1)
+	assert.InDelta(t, 55.0, EntropyBits(Options{Words: 4, Digit: true}), 0.1)
+}
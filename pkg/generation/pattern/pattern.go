@@ -0,0 +1,205 @@
+// Package pattern generates random values of a predictable shape from a regular expression, e.g. svc-[a-z]{6}.
+// Only a bounded subset of the regular expression syntax is supported: literals, character classes, ., groups,
+// alternations, ? and bounded repetitions {n} and {n,m}. Unbounded repetitions, * and + and {n,}, and word
+// boundaries are rejected.
+//
+// Character classes and . generate printable ASCII characters only, other characters of a class are left out. This
+// keeps negated classes such as [^a-z] and \S from generating control characters or arbitrary unicode.
+package pattern
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/entropy"
+)
+
+// MaxLength is the maximum number of characters a pattern may generate
+const MaxLength = 4096
+
+// firstPrintable and lastPrintable bound the printable ASCII characters that classes generate
+const (
+	firstPrintable = ' '
+	lastPrintable  = '~'
+)
+
+// Pattern is a parsed pattern values are generated from
+type Pattern struct {
+	re *syntax.Regexp
+}
+
+// Parse parses a pattern, and returns an error when it uses syntax outside the supported subset or generates
+// values longer than MaxLength
+func Parse(expr string) (*Pattern, error) {
+	if expr == "" {
+		return nil, errors.New("pattern is empty")
+	}
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", expr, err)
+	}
+	length, err := maxLength(re)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", expr, err)
+	}
+	if length == 0 {
+		return nil, fmt.Errorf("invalid pattern %q: it only generates empty values", expr)
+	}
+	return &Pattern{re: re}, nil
+}
+
+// maxLength returns the maximum number of characters generated for the expression, or an error when it is not
+// supported
+func maxLength(re *syntax.Regexp) (int, error) {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText:
+		return 0, nil
+	case syntax.OpLiteral:
+		return len(re.Rune), nil
+	case syntax.OpCharClass:
+		if len(classChars(re)) == 0 {
+			return 0, fmt.Errorf("character class %s has no printable ASCII characters", re)
+		}
+		return 1, nil
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1, nil
+	case syntax.OpCapture, syntax.OpQuest:
+		return maxLength(re.Sub[0])
+	case syntax.OpConcat, syntax.OpAlternate:
+		total := 0
+		for _, sub := range re.Sub {
+			length, err := maxLength(sub)
+			if err != nil {
+				return 0, err
+			}
+			if re.Op == syntax.OpConcat {
+				total += length
+			} else {
+				total = max(total, length)
+			}
+			if total > MaxLength {
+				return 0, fmt.Errorf("values may be longer than %d characters", MaxLength)
+			}
+		}
+		return total, nil
+	case syntax.OpRepeat:
+		if re.Max < 0 {
+			return 0, fmt.Errorf("unbounded repetition %s is not supported, use {n,m}", re)
+		}
+		length, err := maxLength(re.Sub[0])
+		if err != nil {
+			return 0, err
+		}
+		if length > 0 && re.Max > MaxLength/length {
+			return 0, fmt.Errorf("values may be longer than %d characters", MaxLength)
+		}
+		return re.Max * length, nil
+	case syntax.OpStar, syntax.OpPlus:
+		return 0, fmt.Errorf("unbounded repetition %s is not supported, use {n,m}", re)
+	default:
+		return 0, fmt.Errorf("%s is not supported", re)
+	}
+}
+
+// classChars returns the printable ASCII characters of a character class, or all of them for .
+func classChars(re *syntax.Regexp) []rune {
+	ranges := []rune{firstPrintable, lastPrintable}
+	if re.Op == syntax.OpCharClass {
+		ranges = re.Rune
+	}
+	chars := []rune{}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for char := max(ranges[i], firstPrintable); char <= min(ranges[i+1], lastPrintable); char++ {
+			chars = append(chars, char)
+		}
+	}
+	return chars
+}
+
+// Generate returns a value matching the pattern, drawn from random
+func (p *Pattern) Generate(random io.Reader) (string, error) {
+	var value strings.Builder
+	if err := generate(random, &value, p.re); err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+func generate(random io.Reader, value *strings.Builder, re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpLiteral:
+		value.WriteString(string(re.Rune))
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		chars := classChars(re)
+		index, err := entropy.Int(random, len(chars))
+		if err != nil {
+			return err
+		}
+		value.WriteRune(chars[index])
+	case syntax.OpCapture:
+		return generate(random, value, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if err := generate(random, value, sub); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		index, err := entropy.Int(random, len(re.Sub))
+		if err != nil {
+			return err
+		}
+		return generate(random, value, re.Sub[index])
+	case syntax.OpQuest, syntax.OpRepeat:
+		minCount, maxCount := 0, 1
+		if re.Op == syntax.OpRepeat {
+			minCount, maxCount = re.Min, re.Max
+		}
+		count, err := entropy.Int(random, maxCount-minCount+1)
+		if err != nil {
+			return err
+		}
+		for range minCount + count {
+			if err := generate(random, value, re.Sub[0]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// EntropyBits returns the entropy in bits that every value generated from the pattern has at least. Only the
+// character classes count: the choices of alternations and repetitions may lead to the same value, so they are
+// not counted, and of each the choice with the least entropy is used.
+func (p *Pattern) EntropyBits() float64 {
+	return entropyBits(p.re)
+}
+
+func entropyBits(re *syntax.Regexp) float64 {
+	switch re.Op {
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return math.Log2(float64(len(classChars(re))))
+	case syntax.OpCapture:
+		return entropyBits(re.Sub[0])
+	case syntax.OpConcat:
+		bits := 0.0
+		for _, sub := range re.Sub {
+			bits += entropyBits(sub)
+		}
+		return bits
+	case syntax.OpAlternate:
+		bits := math.Inf(1)
+		for _, sub := range re.Sub {
+			bits = min(bits, entropyBits(sub))
+		}
+		return bits
+	case syntax.OpRepeat:
+		return float64(re.Min) * entropyBits(re.Sub[0])
+	default:
+		return 0
+	}
+}
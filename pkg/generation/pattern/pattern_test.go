@@ -0,0 +1,106 @@
+package pattern
+
+import (
+	"crypto/rand"
+	mathrand "math/rand/v2"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		length int
+	}{
+		{name: "username", expr: "svc-[a-z]{6}", length: 10},
+		{name: "access key id", expr: "AKIA[A-Z0-9]{16}", length: 20},
+		{name: "uuid", expr: "[a-f0-9]{8}-[a-f0-9]{4}-4[a-f0-9]{3}-[89ab][a-f0-9]{3}-[a-f0-9]{12}", length: 36},
+		{name: "anchors", expr: `^db_\d{4}$`, length: 7},
+		{name: "alternation", expr: "(?:dev|prd)-[a-z]{3}", length: 7},
+		{name: "optional suffix", expr: "[a-z]{2,4}(-[0-9])?"},
+		{name: "negated class", expr: "[^a-zA-Z0-9]{32}", length: 32},
+		{name: "any character", expr: ".{16}", length: 16},
+		{name: "case insensitive", expr: "(?i)[a-c]{8}", length: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(tt.expr)
+			require.NoError(t, err)
+			matcher := regexp.MustCompile("^(?:" + tt.expr + ")$")
+			for range 50 {
+				value, err := p.Generate(rand.Reader)
+				require.NoError(t, err)
+				assert.Regexp(t, matcher, value)
+				if tt.length > 0 {
+					assert.Len(t, value, tt.length)
+				}
+				for _, char := range value {
+					assert.True(t, char >= ' ' && char <= '~', "%q is not printable ASCII", char)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "", err: "pattern is empty"},
+		{expr: "[a-z", err: "missing closing ]"},
+		{expr: "[a-z]+", err: "unbounded repetition"},
+		{expr: "[a-z]*", err: "unbounded repetition"},
+		{expr: "[a-z]{4,}", err: "unbounded repetition"},
+		{expr: `\bword`, err: "not supported"},
+		{expr: "[a-z]{1000}[a-z]{1000}[a-z]{1000}[a-z]{1000}[a-z]{1000}", err: "longer than 4096 characters"},
+		{expr: "(?:abcdefghij){500}", err: "longer than 4096 characters"},
+		{expr: "[äöü]{4}", err: "no printable ASCII characters"},
+		{expr: "^$", err: "only generates empty values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestGenerateDeterministicReader(t *testing.T) {
+	p, err := Parse("key-[A-Za-z0-9]{24}(-[0-9]{2})?")
+	require.NoError(t, err)
+	generate := func() string {
+		value, err := p.Generate(mathrand.NewChaCha8([32]byte{3}))
+		require.NoError(t, err)
+		return value
+	}
+	assert.Equal(t, generate(), generate())
+}
+
+func TestEntropyBits(t *testing.T) {
+	tests := []struct {
+		expr string
+		bits float64
+	}{
+		{expr: "svc-[a-z]{6}", bits: 28.2},
+		{expr: "[a-f0-9]{32}", bits: 128},
+		{expr: "(?:[a-z]{8}|[0-9]{4})", bits: 13.3},
+		{expr: "[a-z]{4,8}(-[0-9])?", bits: 18.8},
+		{expr: ".", bits: 6.6},
+		{expr: "static", bits: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.bits, p.EntropyBits(), 0.05)
+		})
+	}
+}
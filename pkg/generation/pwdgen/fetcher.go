@@ -0,0 +1,57 @@
+package pwdgen
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cachingFetcher remembers the objects it fetched, so inputs shared by several values or targets are only fetched once
+type cachingFetcher struct {
+	fetcher SecretFetcher
+
+	mu      sync.Mutex
+	objects map[cachingFetcherKey]client.Object
+}
+
+type cachingFetcherKey struct {
+	kind string
+	key  types.NamespacedName
+}
+
+// NewCachingFetcher returns a SecretFetcher that fetches every object only once. Use it for a single render,
+// so changes to inputs are picked up by the next one.
+func NewCachingFetcher(fetcher SecretFetcher) SecretFetcher {
+	return &cachingFetcher{
+		fetcher: fetcher,
+		objects: map[cachingFetcherKey]client.Object{},
+	}
+}
+
+func (f *cachingFetcher) Get(ctx context.Context, key types.NamespacedName, obj client.Object, opts ...client.GetOption) error {
+	cacheKey := cachingFetcherKey{kind: reflect.TypeOf(obj).String(), key: key}
+
+	f.mu.Lock()
+	cached, found := f.objects[cacheKey]
+	f.mu.Unlock()
+	if !found {
+		if err := f.fetcher.Get(ctx, key, obj, opts...); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.objects[cacheKey] = obj.DeepCopyObject().(client.Object)
+		f.mu.Unlock()
+		return nil
+	}
+
+	target := reflect.ValueOf(obj)
+	if target.Kind() != reflect.Pointer || target.Type() != reflect.TypeOf(cached) {
+		return fmt.Errorf("cannot copy cached %T into %T", cached, obj)
+	}
+	target.Elem().Set(reflect.ValueOf(cached.DeepCopyObject()).Elem())
+	return nil
+}
@@ -0,0 +1,339 @@
+package pwdgen
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	v1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/entropy"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/passphrase"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/pattern"
+)
+
+// Characters generated values are made of
+const (
+	LowerLetters = "abcdefghijklmnopqrstuvwxyz"
+	UpperLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits       = "0123456789"
+	// Symbols are the symbols of generated values unless the spec sets its own
+	Symbols = "~!@#$%^&*()_+`-={}|[]\\:\"<>?,./"
+	// AmbiguousCharacters are left out when ambiguous characters are excluded, as they are easily mistaken for one another
+	AmbiguousCharacters = "0O1lI|"
+)
+
+// charset holds the characters of each class a generated value is made of. The characters of an alphabet are sorted
+// into the classes by their unicode category.
+type charset struct {
+	lower   []rune
+	upper   []rune
+	digits  []rune
+	symbols []rune
+	// alphabet is set when the spec sets the full alphabet, values are then drawn from all classes alike
+	alphabet bool
+}
+
+// newCharset returns the characters values are generated from for the spec
+func newCharset(spec *v1.GeneratedValueSpec) charset {
+	var set charset
+	if spec.Alphabet != "" {
+		set.alphabet = true
+		for _, char := range spec.Alphabet {
+			switch {
+			case unicode.IsLower(char):
+				set.lower = append(set.lower, char)
+			case unicode.IsUpper(char):
+				set.upper = append(set.upper, char)
+			case unicode.IsDigit(char):
+				set.digits = append(set.digits, char)
+			default:
+				set.symbols = append(set.symbols, char)
+			}
+		}
+	} else {
+		set.lower = []rune(LowerLetters)
+		if !spec.NoUpper {
+			set.upper = []rune(UpperLetters)
+		}
+		set.digits = []rune(Digits)
+		set.symbols = []rune(Symbols)
+		if spec.Symbols != "" {
+			set.symbols = []rune(spec.Symbols)
+		}
+	}
+
+	exclude := func(chars []rune) []rune {
+		unique := make([]rune, 0, len(chars))
+		for _, char := range chars {
+			if spec.ExcludeAmbiguous && strings.ContainsRune(AmbiguousCharacters, char) {
+				continue
+			}
+			if !slices.Contains(unique, char) {
+				unique = append(unique, char)
+			}
+		}
+		return unique
+	}
+	set.lower = exclude(set.lower)
+	set.upper = exclude(set.upper)
+	set.digits = exclude(set.digits)
+	set.symbols = exclude(set.symbols)
+	return set
+}
+
+// draw is a number of characters drawn from a class of characters
+type draw struct {
+	name  string
+	chars []rune
+	count int
+}
+
+// generatePassword generates a value of the item's length, or of the length that reaches its minimum entropy, with the
+// minimum number of characters of each class. Up to the maximum number of digits and symbols are added, and letters
+// for the remainder. With an alphabet, the remainder is drawn from the whole alphabet.
+func generatePassword(random io.Reader, item *v1.SecretValueItemTemplate) (string, error) {
+	spec := item.Generated
+	length := 0
+	if spec.MinEntropyBits == 0 {
+		var err error
+		if length, err = getPasswordLength(random, item); err != nil {
+			return "", err
+		}
+	}
+	digits, symbols := classCountRange(spec)
+	numDigits, err := getRandomNumberBetween(random, digits[0], digits[1]+1)
+	if err != nil {
+		return "", err
+	}
+	numSymbols, err := getRandomNumberBetween(random, symbols[0], symbols[1]+1)
+	if err != nil {
+		return "", err
+	}
+	draws, err := planDraws(spec, newCharset(spec), length, numDigits, numSymbols)
+	if err != nil {
+		return "", err
+	}
+	return generateRandomString(random, draws, !spec.NoRepeat)
+}
+
+// classCountRange returns the lowest and highest number of digits and symbols of values generated for the spec
+func classCountRange(spec *v1.GeneratedValueSpec) (digits, symbols [2]int) {
+	minDigits, minSymbols := int(spec.MinDigits), int(spec.MinSymbols)
+	if spec.Alphabet != "" {
+		return [2]int{minDigits, minDigits}, [2]int{minSymbols, minSymbols}
+	}
+	digits[0], digits[1] = numberRange(minDigits, max(minDigits, int(spec.MaxDigits)))
+	symbols[0], symbols[1] = numberRange(minSymbols, max(minSymbols, int(spec.MaxSymbols)))
+	return digits, symbols
+}
+
+// planDraws returns the characters to draw for a value of the given length with the given number of digits and
+// symbols, which are reduced to fit the length. With a minimum entropy, the length is not used: letters are added
+// until the value has the minimum entropy.
+func planDraws(spec *v1.GeneratedValueSpec, set charset, length, numDigits, numSymbols int) ([]draw, error) {
+	minLower, minUpper, minDigits, minSymbols := int(spec.MinLower), int(spec.MinUpper), int(spec.MinDigits), int(spec.MinSymbols)
+	if spec.MinEntropyBits > 0 {
+		length = minLower + minUpper + numDigits + numSymbols
+	}
+	if minLower+minUpper+minDigits+minSymbols > length {
+		return nil, fmt.Errorf("minimum number of lower case letters, upper case letters, digits and symbols exceeds the length of %d", length)
+	}
+
+	remainder := draw{name: "characters", chars: slices.Concat(set.lower, set.upper, set.digits, set.symbols)}
+	if !set.alphabet {
+		remainder = draw{name: "letters", chars: slices.Concat(set.lower, set.upper)}
+	}
+	numDigits = min(length-minLower-minUpper-minSymbols, numDigits)
+	numSymbols = min(length-minLower-minUpper-numDigits, numSymbols)
+	remainder.count = length - minLower - minUpper - numDigits - numSymbols
+
+	draws := []draw{
+		{name: "lower case letters", chars: set.lower, count: minLower},
+		{name: "upper case letters", chars: set.upper, count: minUpper},
+		{name: "digits", chars: set.digits, count: numDigits},
+		{name: "symbols", chars: set.symbols, count: numSymbols},
+	}
+	if spec.MinEntropyBits == 0 {
+		return append(draws, remainder), nil
+	}
+
+	bits := drawsEntropy(draws, !spec.NoRepeat)
+	available := availableChars(remainder, draws, !spec.NoRepeat)
+	for bits < float64(spec.MinEntropyBits) {
+		if !spec.NoRepeat {
+			available = len(remainder.chars)
+		}
+		if available < 2 {
+			return nil, fmt.Errorf("not enough %s are available to reach %d bits of entropy", remainder.name, spec.MinEntropyBits)
+		}
+		bits += math.Log2(float64(available))
+		remainder.count++
+		available--
+	}
+	return append(draws, remainder), nil
+}
+
+// drawsEntropy returns the entropy in bits of a value made of the draws. The positions of the characters add to the
+// entropy of a value, but are not counted, so it is a lower bound.
+func drawsEntropy(draws []draw, allowRepeat bool) float64 {
+	bits := 0.0
+	for i, class := range draws {
+		available := availableChars(class, draws[:i], allowRepeat)
+		for range class.count {
+			if available > 1 {
+				bits += math.Log2(float64(available))
+			}
+			if !allowRepeat {
+				available--
+			}
+		}
+	}
+	return bits
+}
+
+// availableChars returns the number of characters a draw is certain to have available after the previous draws.
+// Without repeats, each previous draw may have used as many of its characters as the two have in common.
+func availableChars(class draw, previous []draw, allowRepeat bool) int {
+	available := len(class.chars)
+	if allowRepeat {
+		return available
+	}
+	for _, other := range previous {
+		common := 0
+		for _, char := range other.chars {
+			if slices.Contains(class.chars, char) {
+				common++
+			}
+		}
+		available -= min(other.count, common)
+	}
+	return available
+}
+
+// generateRandomString generates a string with the characters of each draw, each inserted at a random position
+func generateRandomString(random io.Reader, draws []draw, allowRepeat bool) (string, error) {
+	result := []rune{}
+	for _, class := range draws {
+		if class.count == 0 {
+			continue
+		}
+		chars := class.chars
+		if !allowRepeat {
+			// Characters are drawn without replacement, leaving out those another class already used
+			chars = slices.DeleteFunc(slices.Clone(chars), func(char rune) bool {
+				return slices.Contains(result, char)
+			})
+			if class.count > len(chars) {
+				return "", fmt.Errorf("number of %s exceeds available %s and repeats are not allowed", class.name, class.name)
+			}
+		}
+		if len(chars) == 0 {
+			return "", fmt.Errorf("no %s are available to generate from", class.name)
+		}
+
+		for i := 0; i < class.count; i++ {
+			index, err := entropy.Int(random, len(chars))
+			if err != nil {
+				return "", err
+			}
+			char := chars[index]
+			if !allowRepeat {
+				chars = slices.Delete(chars, index, index+1)
+			}
+			position, err := entropy.Int(random, len(result)+1)
+			if err != nil {
+				return "", err
+			}
+			result = slices.Insert(result, position, char)
+		}
+	}
+	return string(result), nil
+}
+
+// maxEntropyCombinations is the number of combinations of digits and symbols above which EntropyBits only evaluates
+// the extremes
+const maxEntropyCombinations = 4096
+
+// EntropyBits returns the entropy in bits that every value generated for the spec has at least, rounded down.
+// It does not depend on the generated value, so it can be reported without revealing it.
+func EntropyBits(spec *v1.GeneratedValueSpec) (int, error) {
+	length := int(max(spec.Length, spec.MinLength))
+	if spec.MaxLength > 0 {
+		length, _ = numberRange(int(spec.MinLength), int(spec.MaxLength))
+	}
+	set := newCharset(spec)
+	digits, symbols := classCountRange(spec)
+	if spec.MinEntropyBits == 0 {
+		// Counts beyond the length are reduced to fit it
+		digits[1] = max(digits[0], min(digits[1], length))
+		symbols[1] = max(symbols[0], min(symbols[1], length))
+	}
+
+	// Every combination of the number of digits and symbols is evaluated, as digits and symbols may add less entropy
+	// than the letters they replace. The entropy is concave in the counts, so with many combinations its minimum is
+	// found at the extremes, where the counts reach their bounds or together fill the length.
+	digitCounts := []int{digits[0], digits[1], clamp(length-int(spec.MinLower)-int(spec.MinUpper)-symbols[1], digits)}
+	symbolCounts := []int{symbols[0], symbols[1]}
+	if (digits[1]-digits[0]+1)*(symbols[1]-symbols[0]+1) <= maxEntropyCombinations {
+		digitCounts, symbolCounts = nil, nil
+		for n := digits[0]; n <= digits[1]; n++ {
+			digitCounts = append(digitCounts, n)
+		}
+		for n := symbols[0]; n <= symbols[1]; n++ {
+			symbolCounts = append(symbolCounts, n)
+		}
+	} else if spec.MinEntropyBits > 0 {
+		// The length follows from the entropy, which is not concave in the counts
+		return int(spec.MinEntropyBits), nil
+	}
+
+	lowest := math.Inf(1)
+	for _, numDigits := range digitCounts {
+		for _, numSymbols := range symbolCounts {
+			draws, err := planDraws(spec, set, length, numDigits, numSymbols)
+			if err != nil {
+				return 0, err
+			}
+			lowest = min(lowest, drawsEntropy(draws, !spec.NoRepeat))
+		}
+	}
+	return int(math.Floor(lowest + 1e-9)), nil
+}
+
+func clamp(value int, bounds [2]int) int {
+	return min(max(value, bounds[0]), bounds[1])
+}
+
+// GeneratedEntropy returns the entropy in bits of each generated key of a target with the given overrides, see
+// EntropyBits, including the passwords of htpasswd values, passphrases and pattern values. Keys whose spec cannot generate a value are left out.
+func GeneratedEntropy(passwordSpec *v1.SecretTemplate, overrides v1.SecretValueItems) map[string]int {
+	bits := map[string]int{}
+	record := func(key string, spec *v1.GeneratedValueSpec) {
+		if entropy, err := EntropyBits(spec); err == nil {
+			bits[key] = entropy
+		}
+	}
+	for name, item := range mergeOverrides(passwordSpec, overrides).Data {
+		switch {
+		case isDerivedItem(&item):
+			if item.Templated == nil && item.Hashed == nil && item.Htpasswd != nil {
+				for _, user := range item.Htpasswd.Users {
+					record(item.Htpasswd.GetPasswordKey(name, user), htpasswdPasswordSpec(item.Htpasswd))
+				}
+			}
+		case item.Value != "" || (item.Static != nil && item.Static.Value != ""):
+		case item.Generated != nil:
+			record(name, item.Generated)
+		case item.Passphrase != nil:
+			bits[name] = int(math.Floor(passphrase.EntropyBits(passphraseOptions(item.Passphrase)) + 1e-9))
+		case item.Pattern != nil:
+			if p, err := pattern.Parse(item.Pattern.Expression); err == nil {
+				bits[name] = int(math.Floor(p.EntropyBits() + 1e-9))
+			}
+		}
+	}
+	return bits
+}
@@ -0,0 +1,222 @@
+package pwdgen
+
+import (
+	"crypto/rand"
+	mathrand "math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1.GeneratedValueSpec
+		allowed string
+		err     string
+	}{
+		{
+			name:    "default characters",
+			spec:    v1.GeneratedValueSpec{Length: 40, MaxDigits: 5, MaxSymbols: 5},
+			allowed: LowerLetters + UpperLetters + Digits + Symbols,
+		},
+		{
+			name:    "no upper case letters",
+			spec:    v1.GeneratedValueSpec{Length: 40, NoUpper: true},
+			allowed: LowerLetters,
+		},
+		{
+			name:    "custom symbols",
+			spec:    v1.GeneratedValueSpec{Length: 40, MaxSymbols: 20, Symbols: "-_."},
+			allowed: LowerLetters + UpperLetters + "-_.",
+		},
+		{
+			name:    "alphabet",
+			spec:    v1.GeneratedValueSpec{Length: 40, MaxDigits: 10, MaxSymbols: 10, Alphabet: "abc123"},
+			allowed: "abc123",
+		},
+		{
+			name:    "ambiguous characters are excluded",
+			spec:    v1.GeneratedValueSpec{Length: 200, MaxDigits: 50, MaxSymbols: 50, ExcludeAmbiguous: true},
+			allowed: strings.NewReplacer("0", "", "O", "", "1", "", "l", "", "I", "", "|", "").Replace(LowerLetters + UpperLetters + Digits + Symbols),
+		},
+		{
+			name:    "ambiguous characters are excluded from the alphabet",
+			spec:    v1.GeneratedValueSpec{Length: 20, Alphabet: "0O1lIab", ExcludeAmbiguous: true},
+			allowed: "ab",
+		},
+		{
+			name:    "every character is used once",
+			spec:    v1.GeneratedValueSpec{Length: 6, Alphabet: "abcdef", NoRepeat: true},
+			allowed: "abcdef",
+		},
+		{
+			name:    "minimum character classes",
+			spec:    v1.GeneratedValueSpec{Length: 8, MinLower: 2, MinUpper: 2, MinDigits: 2, MinSymbols: 2},
+			allowed: LowerLetters + UpperLetters + Digits + Symbols,
+		},
+		{
+			name:    "minimum character classes of an alphabet",
+			spec:    v1.GeneratedValueSpec{Length: 12, Alphabet: "abAB12-_", MinUpper: 3, MinSymbols: 3},
+			allowed: "abAB12-_",
+		},
+		{
+			name: "minimum character classes exceed the length",
+			spec: v1.GeneratedValueSpec{Length: 4, MinLower: 2, MinDigits: 3},
+			err:  "exceeds the length of 4",
+		},
+		{
+			name: "minimum upper case letters without upper case letters",
+			spec: v1.GeneratedValueSpec{Length: 8, NoUpper: true, MinUpper: 1},
+			err:  "no upper case letters are available",
+		},
+		{
+			name: "more characters than the alphabet without repeats",
+			spec: v1.GeneratedValueSpec{Length: 7, Alphabet: "abcdef", NoRepeat: true},
+			err:  "number of characters exceeds available characters",
+		},
+		{
+			name: "no characters left",
+			spec: v1.GeneratedValueSpec{Length: 8, Alphabet: "0O1lI", ExcludeAmbiguous: true},
+			err:  "no characters are available",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := generatePassword(rand.Reader, &v1.SecretValueItemTemplate{Generated: &tt.spec})
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, []rune(value), int(tt.spec.Length))
+			for _, char := range value {
+				assert.Contains(t, tt.allowed, string(char))
+			}
+			assert.GreaterOrEqual(t, countOf(value, LowerLetters), int(tt.spec.MinLower))
+			assert.GreaterOrEqual(t, countOf(value, UpperLetters), int(tt.spec.MinUpper))
+			assert.GreaterOrEqual(t, countOf(value, Digits), int(tt.spec.MinDigits))
+			assert.GreaterOrEqual(t, countOf(value, Symbols), int(tt.spec.MinSymbols))
+			if tt.spec.NoRepeat {
+				for _, char := range value {
+					assert.Equal(t, 1, strings.Count(value, string(char)))
+				}
+			}
+		})
+	}
+}
+
+func TestMinimumCharacterClasses(t *testing.T) {
+	// Without a maximum, a value would often lack digits and symbols
+	spec := &v1.GeneratedValueSpec{Length: 12, MinLower: 1, MinUpper: 1, MinDigits: 1, MinSymbols: 1}
+	for range 100 {
+		value, err := generatePassword(rand.Reader, &v1.SecretValueItemTemplate{Generated: spec})
+		require.NoError(t, err)
+		require.Len(t, value, 12)
+		for _, class := range []string{LowerLetters, UpperLetters, Digits, Symbols} {
+			require.Positive(t, countOf(value, class), value)
+		}
+	}
+}
+
+func TestGenerateRandomStringDraws(t *testing.T) {
+	draws := []draw{
+		{name: "letters", chars: []rune("ab"), count: 5},
+		{name: "digits", chars: []rune("12"), count: 3},
+		{name: "symbols", chars: []rune("-"), count: 2},
+	}
+	value, err := generateRandomString(rand.Reader, draws, true)
+	require.NoError(t, err)
+	assert.Len(t, value, 10)
+	assert.Equal(t, 3, countOf(value, "12"))
+	assert.Equal(t, 2, countOf(value, "-"))
+
+	// Without repeats, characters drawn for a class are not available to the others
+	value, err = generateRandomString(rand.Reader, []draw{
+		{name: "letters", chars: []rune("a"), count: 1},
+		{name: "symbols", chars: []rune("a-"), count: 1},
+	}, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []rune("a-"), []rune(value))
+}
+
+func TestGeneratePasswordDeterministicReader(t *testing.T) {
+	spec := &v1.GeneratedValueSpec{MinLength: 16, MaxLength: 32, MaxDigits: 4, MaxSymbols: 4}
+	generate := func() string {
+		value, err := generatePassword(mathrand.NewChaCha8([32]byte{42}), &v1.SecretValueItemTemplate{Generated: spec})
+		require.NoError(t, err)
+		return value
+	}
+	assert.Equal(t, generate(), generate())
+}
+
+func TestEntropyBits(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   v1.GeneratedValueSpec
+		bits   int
+		length int
+	}{
+		{name: "letters", spec: v1.GeneratedValueSpec{Length: 16}, bits: 91, length: 16},
+		{name: "digits replace letters", spec: v1.GeneratedValueSpec{Length: 20, MaxDigits: 5}, bits: 104, length: 20},
+		{name: "random length", spec: v1.GeneratedValueSpec{MinLength: 10, MaxLength: 20}, bits: 57},
+		{name: "without repeats", spec: v1.GeneratedValueSpec{Length: 6, Alphabet: "abcdef", NoRepeat: true}, bits: 9, length: 6},
+		{name: "minimum entropy", spec: v1.GeneratedValueSpec{MinEntropyBits: 128}, bits: 131, length: 23},
+		{name: "minimum entropy of an alphabet", spec: v1.GeneratedValueSpec{MinEntropyBits: 128, Alphabet: "0123456789abcdef"}, bits: 128, length: 32},
+		{name: "minimum entropy with digits", spec: v1.GeneratedValueSpec{MinEntropyBits: 100, MinDigits: 2, MaxDigits: 8}, bits: 101},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bits, err := EntropyBits(&tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.bits, bits)
+
+			value, err := generatePassword(rand.Reader, &v1.SecretValueItemTemplate{Generated: &tt.spec})
+			require.NoError(t, err)
+			if tt.length != 0 {
+				assert.Len(t, value, tt.length)
+			}
+		})
+	}
+
+	t.Run("unreachable minimum entropy", func(t *testing.T) {
+		spec := &v1.GeneratedValueSpec{MinEntropyBits: 10, Alphabet: "ab", NoRepeat: true}
+		_, err := generatePassword(rand.Reader, &v1.SecretValueItemTemplate{Generated: spec})
+		assert.ErrorContains(t, err, "bits of entropy")
+	})
+}
+
+func TestGeneratedEntropy(t *testing.T) {
+	spec := &v1.SecretTemplate{Data: map[string]v1.SecretValueItemTemplate{
+		"password": {Generated: &v1.GeneratedValueSpec{Length: 16}},
+		"static":   {Value: "value", Generated: &v1.GeneratedValueSpec{Length: 16}},
+		"auth":     {Htpasswd: &v1.HtpasswdValueSpec{Users: []string{"admin"}}},
+		"hash":     {Hashed: &v1.HashedValueSpec{Key: "password"}},
+		"invalid":  {Generated: &v1.GeneratedValueSpec{Length: 2, MinDigits: 3}},
+		"words":    {Passphrase: &v1.PassphraseValueSpec{Digit: true}},
+		"id":       {Pattern: &v1.PatternValueSpec{Expression: "AKIA[A-Z0-9]{16}"}},
+		"pattern":  {Pattern: &v1.PatternValueSpec{Expression: "[a-z]+"}},
+	}}
+	assert.Equal(t, map[string]int{"password": 91, "auth.admin": 182, "words": 80, "id": 82}, GeneratedEntropy(spec, nil))
+
+	overrides := v1.SecretValueItems{"password": {Generated: &v1.GeneratedValueSpec{MinEntropyBits: 256}}}
+	assert.Equal(t, map[string]int{"password": 256, "auth.admin": 182, "words": 80, "id": 82}, GeneratedEntropy(spec, overrides))
+}
+
+// countOf returns the number of characters of the value that are in chars
+func countOf(value, chars string) int {
+	count := 0
+	for _, char := range value {
+		if strings.ContainsRune(chars, char) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,176 @@
+package pwdgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	v1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/htpasswd"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/templated"
+)
+
+// templateDependencies returns, for each templated or hashed key, the templated or hashed sibling keys it refers to
+func templateDependencies(passwordSpec *v1.SecretTemplate, templatedKeys []string) (map[string][]string, error) {
+	isTemplated := map[string]bool{}
+	for _, name := range templatedKeys {
+		isTemplated[name] = true
+	}
+
+	emitted, err := emittedKeys(passwordSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := map[string][]string{}
+	for _, name := range templatedKeys {
+		item := passwordSpec.Data[name]
+		keys, all, err := derivedReferences(&item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate templated value for key %s: %w", name, err)
+		}
+		if all {
+			// The whole of .Self is used, so every other templated key has to be rendered first
+			keys = []string{}
+			for _, other := range templatedKeys {
+				if other != name {
+					keys = append(keys, other)
+				}
+			}
+		}
+
+		for _, key := range keys {
+			if owner, found := emitted[key]; found {
+				key = owner
+			}
+			if _, found := passwordSpec.Data[key]; !found {
+				return nil, fmt.Errorf("templated value for key %s refers to unknown key %s", name, key)
+			}
+			if isTemplated[key] {
+				dependencies[name] = append(dependencies[name], key)
+			}
+		}
+	}
+	return dependencies, nil
+}
+
+// derivedReferences returns the sibling keys a derived value refers to, and whether it refers to all of them.
+// Templates refer to keys through .Self, hashed values to the key they hash and docker configs to their password key.
+func derivedReferences(item *v1.SecretValueItemTemplate) ([]string, bool, error) {
+	switch {
+	case item.Templated != nil:
+		return templated.SelfReferences(item.Templated.Template)
+	case item.Hashed != nil:
+		return []string{item.Hashed.Key}, false, nil
+	case item.Htpasswd == nil && item.DockerConfig != nil:
+		return []string{item.DockerConfig.PasswordKey}, false, nil
+	}
+	return nil, false, nil
+}
+
+// emittedKeys returns the keys that items set besides their own key, such as the passwords of the users of an
+// htpasswd value, mapped to the key of the item that sets them
+func emittedKeys(passwordSpec *v1.SecretTemplate) (map[string]string, error) {
+	emitted := map[string]string{}
+	for _, name := range sortedKeys(passwordSpec.Data) {
+		item := passwordSpec.Data[name]
+		keys, err := itemEmittedKeys(name, &item)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if errs := validation.IsConfigMapKey(key); len(errs) != 0 {
+				return nil, fmt.Errorf("key %q set by key %s is not a valid secret key: %s", key, name, strings.Join(errs, ", "))
+			}
+			if _, found := passwordSpec.Data[key]; found {
+				return nil, fmt.Errorf("key %s set by key %s is defined in data as well", key, name)
+			}
+			if other, found := emitted[key]; found {
+				return nil, fmt.Errorf("key %s is set by both key %s and key %s", key, other, name)
+			}
+			emitted[key] = name
+		}
+	}
+	return emitted, nil
+}
+
+// itemEmittedKeys returns the keys an item sets besides its own key
+func itemEmittedKeys(name string, item *v1.SecretValueItemTemplate) ([]string, error) {
+	// Templates and hashes take precedence when an item sets several sources
+	if !isDerivedItem(item) || item.Templated != nil || item.Hashed != nil {
+		return nil, nil
+	}
+	if item.Htpasswd != nil {
+		keys := make([]string, 0, len(item.Htpasswd.Users))
+		for _, user := range item.Htpasswd.Users {
+			if err := htpasswd.ValidateUser(user); err != nil {
+				return nil, fmt.Errorf("failed to generate htpasswd value for key %s: %w", name, err)
+			}
+			keys = append(keys, item.Htpasswd.GetPasswordKey(name, user))
+		}
+		return keys, nil
+	}
+	if item.DockerConfig.HtpasswdKey != "" {
+		return []string{item.DockerConfig.HtpasswdKey}, nil
+	}
+	return nil, nil
+}
+
+// templateOrder returns the templated keys in the order they need to be rendered, so every key is rendered after
+// the sibling keys it refers to, together with the dependencies of every key. An error is returned when the references contain a cycle.
+func templateOrder(passwordSpec *v1.SecretTemplate, templatedKeys []string) ([]string, map[string][]string, error) {
+	dependencies, err := templateDependencies(passwordSpec, templatedKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	order := make([]string, 0, len(templatedKeys))
+	path := []string{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for i, key := range path {
+				if key == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("templated values refer to each other in a cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range dependencies[name] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	sorted := append([]string{}, templatedKeys...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if err := visit(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	return order, dependencies, nil
+}
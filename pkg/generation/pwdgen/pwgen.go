@@ -1,158 +1,984 @@
 package pwdgen
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"fmt"
+	"io"
 	"math"
-	"math/rand"
+	"slices"
+	"sort"
 
-	password "github.com/sethvargo/go-password/password"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	v1 "github.com/containerinfra/kube-secrets-operator/api/v1"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/dockerconfig"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/entropy"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/hashing"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/htpasswd"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/passphrase"
+	"github.com/containerinfra/kube-secrets-operator/pkg/generation/pattern"
 	"github.com/containerinfra/kube-secrets-operator/pkg/generation/templated"
 )
 
-// SecretFetcher is an interface for fetching secrets from Kubernetes
+const (
+	// InputKindSecret is the kind of template inputs that are secrets
+	InputKindSecret = "Secret"
+	// InputKindConfigMap is the kind of template inputs that are config maps
+	InputKindConfigMap = "ConfigMap"
+
+	// DocumentKey is the key under which the inputs of the document template are recorded.
+	// Secret keys cannot contain an @, so it never clashes with the key of a templated value.
+	DocumentKey = "@document"
+)
+
+// SecretFetcher is an interface for fetching secrets and config maps from Kubernetes
 type SecretFetcher interface {
 	Get(ctx context.Context, key types.NamespacedName, obj client.Object, opts ...client.GetOption) error
 }
 
 // GeneratePasswords generates a series of random string based on the supplied password templates
 // This function only handles static and generated values (not templated)
-func GeneratePasswords(passwordSpec *v1.SecretTemplate) map[string][]byte {
+func GeneratePasswords(passwordSpec *v1.SecretTemplate) (map[string][]byte, error) {
 	data := map[string][]byte{}
 
 	for name, item := range passwordSpec.Data {
-		// Direct value field (preferred)
-		if item.Value != "" {
-			data[name] = []byte(item.Value)
-			continue
-		}
-		// Legacy static value (for backward compatibility)
-		if item.Static != nil && item.Static.Value != "" {
-			data[name] = []byte(item.Static.Value)
-			continue
-		}
-		if item.Generated == nil {
-			continue
-		}
-		passwordLength := getPasswordLength(&item)
-		generatedPassword, err := password.Generate(passwordLength, getNumberOfDigits(&item), getNumberOfSymbols(&item), item.Generated.NoUpper, !item.Generated.NoRepeat)
+		value, ok, err := generateValue(name, &item)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+		if ok {
+			data[name] = value
 		}
-		data[name] = []byte(generatedPassword)
 	}
-	return data
+	return data, nil
+}
+
+// RenderedInputs holds, by key, the versions of the inputs each templated value was rendered from
+type RenderedInputs map[string][]v1.TemplateInputVersion
+
+// RenderFilter decides whether a templated value is rendered again, given the versions of the inputs it would be rendered from
+type RenderFilter func(key string, inputs []v1.TemplateInputVersion) bool
+
+// RenderContext describes what templated values are rendered for
+type RenderContext struct {
+	// GeneratedSecret is the GeneratedSecret the values belong to. Its namespace is the default namespace of inputs
+	GeneratedSecret types.NamespacedName
+	// Target is the target secret to render for, see GenerateTargetValues
+	Target *templated.Target
+	// Filter decides whether existing values are rendered again. Without a filter all values are rendered
+	Filter RenderFilter
+	// Changed lists the keys whose values changed since they were last rendered, values referring to them are rendered again
+	Changed []string
+	// Shared holds the values shared by all targets. A value that is specific to a target but still equals the shared
+	// value was not generated for the target yet, e.g. because the target is new or the override was added later
+	Shared map[string][]byte
+	// Limits bounds the execution time and output size of each template
+	Limits templated.Limits
 }
 
 // GenerateValues generates all secret values including templated ones
 // This requires access to the Kubernetes client to fetch input secrets for templating
+// Values that are specific to a target are not included, see GenerateTargetValues
 func GenerateValues(ctx context.Context, fetcher SecretFetcher, defaultNamespace string, passwordSpec *v1.SecretTemplate) (map[string][]byte, error) {
+	data, _, err := GenerateValuesWithInputs(ctx, fetcher, passwordSpec, RenderContext{
+		GeneratedSecret: types.NamespacedName{Namespace: defaultNamespace},
+	})
+	return data, err
+}
+
+// GenerateValuesWithInputs generates all values that are shared by all targets, and returns the versions of the inputs
+// the templated values were rendered from.
+// Templated values are rendered last, in dependency order, so they can refer to the other keys through .Self
+func GenerateValuesWithInputs(ctx context.Context, fetcher SecretFetcher, passwordSpec *v1.SecretTemplate, renderContext RenderContext) (map[string][]byte, RenderedInputs, error) {
 	data := map[string][]byte{}
 
 	for name, item := range passwordSpec.Data {
-		// Handle direct value field (preferred)
-		if item.Value != "" {
-			data[name] = []byte(item.Value)
+		// Templated and hashed values are rendered once all other values are known
+		if isDerivedItem(&item) {
 			continue
 		}
+		value, ok, err := generateValue(name, &item)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			data[name] = value
+		}
+	}
+
+	// Handle templated values
+	return RenderTemplatedValues(ctx, fetcher, passwordSpec, data, renderContext)
+}
 
-		// Handle legacy static values (for backward compatibility)
-		if item.Static != nil && item.Static.Value != "" {
-			data[name] = []byte(item.Static.Value)
+// GenerateMissingValues generates the static and generated values that are missing from the given values.
+// Templated values are not rendered, see RenderTemplatedValues. The given values are not modified.
+func GenerateMissingValues(passwordSpec *v1.SecretTemplate, values map[string][]byte) (map[string][]byte, error) {
+	data := copyValues(values)
+	for name, item := range passwordSpec.Data {
+		if _, exists := data[name]; exists || isDerivedItem(&item) {
 			continue
 		}
+		value, ok, err := generateValue(name, &item)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			data[name] = value
+		}
+	}
+	return data, nil
+}
 
-		// Handle templated values
-		if item.Templated != nil {
-			value, err := generateTemplatedValue(ctx, fetcher, defaultNamespace, item.Templated)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate templated value for key %s: %w", name, err)
+// generateValue generates the value of a static, generated, passphrase or pattern item. False is returned for items without a value
+func generateValue(name string, item *v1.SecretValueItemTemplate) ([]byte, bool, error) {
+	// Handle direct value field (preferred)
+	if item.Value != "" {
+		return []byte(item.Value), true, nil
+	}
+
+	// Handle legacy static values (for backward compatibility)
+	if item.Static != nil && item.Static.Value != "" {
+		return []byte(item.Static.Value), true, nil
+	}
+
+	// Handle generated values
+	if item.Generated != nil {
+		generatedPassword, err := generatePassword(rand.Reader, item)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to generate password for key %s: %w", name, err)
+		}
+		return []byte(generatedPassword), true, nil
+	}
+
+	// Handle passphrases
+	if item.Passphrase != nil {
+		generatedPassphrase, err := passphrase.Generate(rand.Reader, passphraseOptions(item.Passphrase))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to generate passphrase for key %s: %w", name, err)
+		}
+		return []byte(generatedPassphrase), true, nil
+	}
+
+	// Handle pattern values
+	if item.Pattern != nil {
+		generatedValue, err := generatePattern(item.Pattern)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to generate value for key %s: %w", name, err)
+		}
+		return []byte(generatedValue), true, nil
+	}
+	return nil, false, nil
+}
+
+// generatePattern generates a value matching the pattern
+func generatePattern(spec *v1.PatternValueSpec) (string, error) {
+	p, err := pattern.Parse(spec.Expression)
+	if err != nil {
+		return "", err
+	}
+	return p.Generate(rand.Reader)
+}
+
+// passphraseOptions returns the options of a passphrase value
+func passphraseOptions(spec *v1.PassphraseValueSpec) passphrase.Options {
+	return passphrase.Options{
+		Words:      spec.Words,
+		Separator:  spec.GetSeparator(),
+		Capitalize: spec.Capitalize,
+		Digit:      spec.Digit,
+	}
+}
+
+// KeyScope tells how the value of a key of a secret is managed
+type KeyScope int
+
+const (
+	// KeyUnmanaged keys are not part of the spec, e.g. because they were removed from it
+	KeyUnmanaged KeyScope = iota
+	// KeyShared keys hold the same value in every target
+	KeyShared
+	// KeyTargetSpecific keys hold a value that is specific to the target: an override, a templated value referring
+	// to an override or to .Target directly or through a sibling key, or a key emitted by such a document template
+	KeyTargetSpecific
+)
+
+// KeyScopes returns a function that reports the scope of each key of a target with the given overrides.
+// The keys emitted by the document template are not known upfront, so keys that are not part of the spec are
+// attributed to the document when there is one.
+func KeyScopes(passwordSpec *v1.SecretTemplate, overrides v1.SecretValueItems) (func(key string) KeyScope, error) {
+	merged := mergeOverrides(passwordSpec, overrides)
+	plan, err := planTemplates(merged, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return func(key string) KeyScope {
+		if owner, found := plan.emitted[key]; found {
+			key = owner
+		}
+		if _, found := merged.Data[key]; found {
+			if plan.perTarget[key] {
+				return KeyTargetSpecific
 			}
+			return KeyShared
+		}
+		switch {
+		case plan.document == nil:
+			return KeyUnmanaged
+		case plan.document.perTarget:
+			return KeyTargetSpecific
+		}
+		return KeyShared
+	}, nil
+}
+
+// RefersToTarget returns true if a templated value or the document template of a target with the given overrides
+// refers to .Target
+func RefersToTarget(passwordSpec *v1.SecretTemplate, overrides v1.SecretValueItems) (bool, error) {
+	for name, item := range mergeOverrides(passwordSpec, overrides).Data {
+		if !isTemplatedItem(&item) {
+			continue
+		}
+		found, err := templated.ReferencesTarget(item.Templated.Template)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate templated value for key %s: %w", name, err)
+		}
+		if found {
+			return true, nil
+		}
+	}
+	if passwordSpec.Document != nil {
+		found, err := templated.ReferencesTarget(passwordSpec.Document.Template)
+		if err != nil {
+			return false, fmt.Errorf("failed to generate document: %w", err)
+		}
+		return found, nil
+	}
+	return false, nil
+}
+
+// RenderTemplatedValues renders the templated values that are shared by all targets on top of the given values, in
+// dependency order. Existing values are only rendered again when the filter of the render context allows it, or when
+// a sibling value they refer to through .Self changed. Missing values are always rendered. The given values are not modified.
+func RenderTemplatedValues(ctx context.Context, fetcher SecretFetcher, passwordSpec *v1.SecretTemplate, values map[string][]byte, renderContext RenderContext) (map[string][]byte, RenderedInputs, error) {
+	plan, err := planTemplates(passwordSpec, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return renderTemplates(ctx, fetcher, passwordSpec, plan, copyValues(values), renderContext, false, nil)
+}
+
+// GenerateTargetValues generates the values that are specific to a single target, see TargetKeys, on top of the given
+// values of the target. Static overrides are always set, generated overrides only when they are missing or still hold
+// the shared value, and templated values are rendered as by RenderTemplatedValues. The given values are not modified.
+func GenerateTargetValues(ctx context.Context, fetcher SecretFetcher, passwordSpec *v1.SecretTemplate, overrides v1.SecretValueItems, values map[string][]byte, renderContext RenderContext) (map[string][]byte, RenderedInputs, error) {
+	merged := mergeOverrides(passwordSpec, overrides)
+	plan, err := planTemplates(merged, overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := copyValues(values)
+	notGenerated := map[string]bool{}
+	for name := range plan.perTarget {
+		current, exists := data[name]
+		shared, isShared := renderContext.Shared[name]
+		notGenerated[name] = !exists || (isShared && bytes.Equal(current, shared))
+	}
+
+	changed := append([]string{}, renderContext.Changed...)
+	for _, name := range sortedKeys(overrides) {
+		item := overrides[name]
+		if isDerivedItem(&item) {
+			continue
+		}
+		isStatic := item.Value != "" || (item.Static != nil && item.Static.Value != "")
+		if !isStatic && !notGenerated[name] {
+			continue
+		}
+		value, ok, err := generateValue(name, &item)
+		if err != nil {
+			return nil, nil, err
+		}
+		if current, exists := data[name]; ok && (!exists || !bytes.Equal(current, value)) {
 			data[name] = value
+			changed = append(changed, name)
+		}
+	}
+	renderContext.Changed = changed
+	return renderTemplates(ctx, fetcher, merged, plan, data, renderContext, true, notGenerated)
+}
+
+// renderTemplates renders the templated values of the plan that are either specific to a target or shared, into data.
+// Values marked as forced are rendered regardless of the filter of the render context.
+func renderTemplates(ctx context.Context, fetcher SecretFetcher, passwordSpec *v1.SecretTemplate, plan *templatePlan, data map[string][]byte, renderContext RenderContext, perTarget bool, forced map[string]bool) (map[string][]byte, RenderedInputs, error) {
+	changed := map[string]bool{}
+	for _, key := range renderContext.Changed {
+		changed[key] = true
+	}
+	inputs := RenderedInputs{}
+	for _, name := range plan.order {
+		if plan.perTarget[name] != perTarget {
 			continue
 		}
 
-		// Handle generated values
-		if item.Generated != nil {
-			passwordLength := getPasswordLength(&item)
-			generatedPassword, err := password.Generate(passwordLength, getNumberOfDigits(&item), getNumberOfSymbols(&item), item.Generated.NoUpper, !item.Generated.NoRepeat)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate password for key %s: %w", name, err)
+		item := passwordSpec.Data[name]
+		if !isTemplatedItem(&item) {
+			if err := renderDerivedValue(name, &item, plan, data, renderContext, forced, changed); err != nil {
+				return nil, nil, err
 			}
-			data[name] = []byte(generatedPassword)
 			continue
 		}
+
+		spec := item.Templated
+		templateData, versions, err := fetchTemplateInputs(ctx, fetcher, renderContext.GeneratedSecret.Namespace, spec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate templated value for key %s: %w", name, err)
+		}
+		inputs[name] = versions
+
+		current, exists := data[name]
+		render := !exists || forced[name] || renderContext.Filter == nil || renderContext.Filter(name, versions)
+		for _, key := range plan.references[name] {
+			render = render || changed[key]
+		}
+		if !render {
+			continue
+		}
+
+		templateData.Self = templated.NewInputValues(data)
+		templateData.GeneratedSecret = templated.Object{
+			Name:      renderContext.GeneratedSecret.Name,
+			Namespace: renderContext.GeneratedSecret.Namespace,
+		}
+		if renderContext.Target != nil {
+			templateData.Target = *renderContext.Target
+		}
+		value, err := templated.Render(spec.Template, templateData, templated.Options{
+			MissingKey: missingKeyOption(spec.MissingKeyPolicy),
+			Limits:     renderContext.Limits,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate templated value for key %s: failed to render template: %w", name, err)
+		}
+		changed[name] = !exists || !bytes.Equal(current, value)
+		data[name] = value
 	}
 
-	return data, nil
+	if plan.document != nil && plan.document.perTarget == perTarget {
+		if err := renderDocument(ctx, fetcher, passwordSpec, plan, data, renderContext, changed, inputs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := checkSecretSize(passwordSpec, plan, data); err != nil {
+		return nil, nil, err
+	}
+	return data, inputs, nil
+}
+
+// checkSecretSize returns an error when the values exceed the size limit of a secret, which the API server would reject.
+// It is a template error only when the values that are not rendered from a template are within the limit.
+func checkSecretSize(passwordSpec *v1.SecretTemplate, plan *templatePlan, data map[string][]byte) error {
+	size, rendered := 0, 0
+	for key, value := range data {
+		size += len(value)
+		if isRenderedKey(passwordSpec, plan, key) {
+			rendered += len(value)
+		}
+	}
+	if size <= corev1.MaxSecretSize {
+		return nil
+	}
+	if size-rendered > corev1.MaxSecretSize {
+		return fmt.Errorf("values are %d bytes, exceeding the secret size limit of %d bytes", size, corev1.MaxSecretSize)
+	}
+	return &templated.Error{Err: fmt.Errorf("rendered values are %d bytes, exceeding the secret size limit of %d bytes", size, corev1.MaxSecretSize)}
+}
+
+// isRenderedKey returns true when the value of the key is rendered from a template, either a templated value or a key
+// of the document
+func isRenderedKey(passwordSpec *v1.SecretTemplate, plan *templatePlan, key string) bool {
+	if item, defined := passwordSpec.Data[key]; defined {
+		return item.Templated != nil
+	}
+	if _, emitted := plan.emitted[key]; emitted {
+		return false
+	}
+	return plan.document != nil
+}
+
+// renderDerivedValue renders a hashed, htpasswd or docker config value into data. As templated values, existing values
+// are only rendered again when forced, without a filter, or when a value they refer to changed. Htpasswd values are
+// rendered as well when their users changed, and docker configs when their htpasswd file is missing.
+func renderDerivedValue(name string, item *v1.SecretValueItemTemplate, plan *templatePlan, data map[string][]byte, renderContext RenderContext, forced map[string]bool, changed map[string]bool) error {
+	current, exists := data[name]
+	render := !exists || forced[name] || renderContext.Filter == nil
+	for _, key := range plan.references[name] {
+		render = render || changed[key]
+	}
+
+	switch {
+	case item.Hashed != nil:
+		if !render {
+			return nil
+		}
+		value, err := hashValue(name, item.Hashed, data)
+		if err != nil {
+			return err
+		}
+		data[name] = value
+	case item.Htpasswd != nil:
+		if !render && !htpasswdOutdated(name, item.Htpasswd, data, forced) {
+			return nil
+		}
+		if err := renderHtpasswd(name, item.Htpasswd, data, forced, changed); err != nil {
+			return err
+		}
+	default:
+		htpasswdKey := item.DockerConfig.HtpasswdKey
+		if _, exists := data[htpasswdKey]; !render && (htpasswdKey == "" || (exists && !forced[htpasswdKey])) {
+			return nil
+		}
+		if err := renderDockerConfig(name, item.DockerConfig, data, changed); err != nil {
+			return err
+		}
+	}
+	changed[name] = !exists || !bytes.Equal(current, data[name])
+	return nil
 }
 
-// generateTemplatedValue fetches the input secret and renders the template
-func generateTemplatedValue(ctx context.Context, fetcher SecretFetcher, defaultNamespace string, spec *v1.TemplatedValueSpec) ([]byte, error) {
-	if spec.InputSecretRef == nil {
-		return nil, fmt.Errorf("inputSecretRef is required for templated values")
+// renderDockerConfig renders the docker config JSON of a docker config value into data, together with its htpasswd
+// file when it has one
+func renderDockerConfig(name string, spec *v1.DockerConfigValueSpec, data map[string][]byte, changed map[string]bool) error {
+	password, found := data[spec.PasswordKey]
+	if !found {
+		return fmt.Errorf("failed to generate docker config for key %s: key %s has no value", name, spec.PasswordKey)
+	}
+	config, err := dockerconfig.Render(spec.Registry, spec.Username, string(password), spec.Email)
+	if err != nil {
+		return fmt.Errorf("failed to generate docker config for key %s: %w", name, err)
+	}
+	data[name] = config
+
+	if spec.HtpasswdKey == "" {
+		return nil
+	}
+	current, exists := data[spec.HtpasswdKey]
+	file, err := htpasswd.Render([]string{spec.Username}, map[string][]byte{spec.Username: password}, current, 0)
+	if err != nil {
+		return fmt.Errorf("failed to generate docker config for key %s: %w", name, err)
 	}
+	data[spec.HtpasswdKey] = file
+	changed[spec.HtpasswdKey] = !exists || !bytes.Equal(current, file)
+	return nil
+}
+
+// hashValue returns the hash of the key the hashed value refers to. The current hash is kept when it still matches the
+// value of the key and the parameters, so hashes with a random salt do not change on every render.
+func hashValue(name string, spec *v1.HashedValueSpec, data map[string][]byte) ([]byte, error) {
+	source, found := data[spec.Key]
+	if !found {
+		return nil, fmt.Errorf("failed to generate hashed value for key %s: key %s has no value", name, spec.Key)
+	}
+	params := hashing.Params{
+		Algorithm:   hashing.Algorithm(spec.Algorithm),
+		Cost:        spec.Cost,
+		Iterations:  spec.Iterations,
+		Memory:      spec.Memory,
+		Parallelism: spec.Parallelism,
+	}
+	if current, exists := data[name]; exists && hashing.Verify(string(current), source, params) {
+		return current, nil
+	}
+	hashed, err := hashing.Hash(rand.Reader, source, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate hashed value for key %s: %w", name, err)
+	}
+	return []byte(hashed), nil
+}
+
+// defaultHtpasswdPasswordLength is the length of the passwords of htpasswd users without a password spec
+const defaultHtpasswdPasswordLength = 32
 
+// htpasswdOutdated returns true when the users of the htpasswd file in data differ from the spec, or the password of
+// a user is missing or has to be generated
+func htpasswdOutdated(name string, spec *v1.HtpasswdValueSpec, data map[string][]byte, forced map[string]bool) bool {
+	if !slices.Equal(htpasswd.Users(data[name]), spec.Users) {
+		return true
+	}
+	for _, user := range spec.Users {
+		key := spec.GetPasswordKey(name, user)
+		if _, exists := data[key]; !exists || forced[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// htpasswdPasswordSpec returns the spec the passwords of the users of an htpasswd value are generated with
+func htpasswdPasswordSpec(spec *v1.HtpasswdValueSpec) *v1.GeneratedValueSpec {
+	if spec.Password == nil {
+		return &v1.GeneratedValueSpec{Length: defaultHtpasswdPasswordLength}
+	}
+	return spec.Password
+}
+
+// renderHtpasswd generates the passwords of the users of an htpasswd value that are missing or forced, and renders
+// the htpasswd file with their hashes into data. Passwords that are generated are marked as changed.
+func renderHtpasswd(name string, spec *v1.HtpasswdValueSpec, data map[string][]byte, forced map[string]bool, changed map[string]bool) error {
+	passwordItem := v1.SecretValueItemTemplate{Generated: htpasswdPasswordSpec(spec)}
+
+	passwords := make(map[string][]byte, len(spec.Users))
+	for _, user := range spec.Users {
+		key := spec.GetPasswordKey(name, user)
+		if _, exists := data[key]; !exists || forced[key] {
+			password, _, err := generateValue(key, &passwordItem)
+			if err != nil {
+				return fmt.Errorf("failed to generate htpasswd value for key %s: %w", name, err)
+			}
+			data[key] = password
+			changed[key] = true
+		}
+		passwords[user] = data[key]
+	}
+
+	file, err := htpasswd.Render(spec.Users, passwords, data[name], spec.Cost)
+	if err != nil {
+		return fmt.Errorf("failed to generate htpasswd value for key %s: %w", name, err)
+	}
+	data[name] = file
+	return nil
+}
+
+// renderDocument renders the document template into data. The document is always rendered, so the keys it emits are
+// known, but existing keys are only replaced under the same conditions as templated values, or when they still hold
+// the shared values. This keeps values that differ on every render, such as bcrypt hashes, stable.
+func renderDocument(ctx context.Context, fetcher SecretFetcher, passwordSpec *v1.SecretTemplate, plan *templatePlan, data map[string][]byte, renderContext RenderContext, changed map[string]bool, inputs RenderedInputs) error {
+	spec := passwordSpec.Document
+	templateData, versions, err := fetchTemplateInputs(ctx, fetcher, renderContext.GeneratedSecret.Namespace, &spec.TemplatedValueSpec)
+	if err != nil {
+		return fmt.Errorf("failed to generate document: %w", err)
+	}
+	inputs[DocumentKey] = versions
+
+	templateData.Self = templated.NewInputValues(data)
+	templateData.GeneratedSecret = templated.Object{
+		Name:      renderContext.GeneratedSecret.Name,
+		Namespace: renderContext.GeneratedSecret.Namespace,
+	}
+	if renderContext.Target != nil {
+		templateData.Target = *renderContext.Target
+	}
+	rendered, err := templated.Render(spec.Template, templateData, templated.Options{
+		MissingKey: missingKeyOption(spec.MissingKeyPolicy),
+		Limits:     renderContext.Limits,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate document: failed to render template: %w", err)
+	}
+	values, err := templated.ParseDocument(rendered, documentFormat(spec.Format))
+	if err != nil {
+		return fmt.Errorf("failed to generate document: %w", err)
+	}
+
+	apply := renderContext.Filter == nil || renderContext.Filter(DocumentKey, versions)
+	for _, key := range plan.document.references {
+		apply = apply || changed[key]
+	}
+	copied := len(values) != 0
+	for key := range values {
+		if _, defined := passwordSpec.Data[key]; defined {
+			return &templated.Error{Err: fmt.Errorf("failed to generate document: key %s is defined in data as well", key)}
+		}
+		if owner, emitted := plan.emitted[key]; emitted {
+			return &templated.Error{Err: fmt.Errorf("failed to generate document: key %s is set by key %s as well", key, owner)}
+		}
+		current, exists := data[key]
+		shared, isShared := renderContext.Shared[key]
+		apply = apply || !exists
+		copied = copied && exists && isShared && bytes.Equal(current, shared)
+	}
+	if !apply && !copied {
+		return nil
+	}
+	for key, value := range values {
+		data[key] = value
+	}
+	return nil
+}
+
+// documentFormat converts the format of the document template to the format it is parsed in
+func documentFormat(format v1.DocumentFormat) templated.DocumentFormat {
+	if format == v1.DocumentFormatJSON {
+		return templated.DocumentFormatJSON
+	}
+	return templated.DocumentFormatYAML
+}
+
+// templatePlan describes how the templated values of a spec are rendered
+type templatePlan struct {
+	// order lists the templated keys in the order they are rendered
+	order []string
+	// references holds the sibling keys each templated key refers to through .Self
+	references map[string][]string
+	// perTarget marks the keys that are specific to each target
+	perTarget map[string]bool
+	// emitted maps the keys set by items besides their own key to the key of the item, see emittedKeys
+	emitted map[string]string
+	// document describes how the document template is rendered, if there is one
+	document *documentPlan
+}
+
+// documentPlan describes how the document template is rendered, after all templated values
+type documentPlan struct {
+	// references holds the keys the document refers to through .Self
+	references []string
+	// perTarget is true when the document is specific to each target
+	perTarget bool
+}
+
+// planTemplates determines the render order of the templated keys and which of them are specific to each target,
+// given the items that are overridden for the target
+func planTemplates(passwordSpec *v1.SecretTemplate, overrides v1.SecretValueItems) (*templatePlan, error) {
+	templatedKeys := []string{}
+	for name, item := range passwordSpec.Data {
+		if isDerivedItem(&item) {
+			templatedKeys = append(templatedKeys, name)
+		}
+	}
+	order, _, err := templateOrder(passwordSpec, templatedKeys)
+	if err != nil {
+		return nil, err
+	}
+	emitted, err := emittedKeys(passwordSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	references := map[string][]string{}
+	perTarget := map[string]bool{}
+	for name := range overrides {
+		perTarget[name] = true
+	}
+	// References are rendered first, so a key is specific to each target when it or one of its references is
+	// overridden or refers to .Target
+	for _, name := range order {
+		item := passwordSpec.Data[name]
+		keys, all, err := derivedReferences(&item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate templated value for key %s: %w", name, err)
+		}
+		if all {
+			keys = []string{}
+			for other := range passwordSpec.Data {
+				if other != name {
+					keys = append(keys, other)
+				}
+			}
+			sort.Strings(keys)
+		}
+		for i, key := range keys {
+			if owner, found := emitted[key]; found {
+				keys[i] = owner
+			}
+		}
+		references[name] = keys
+
+		if isTemplatedItem(&item) {
+			refersToTarget, err := templated.ReferencesTarget(item.Templated.Template)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate templated value for key %s: %w", name, err)
+			}
+			perTarget[name] = perTarget[name] || refersToTarget
+		}
+		for _, key := range keys {
+			perTarget[name] = perTarget[name] || perTarget[key]
+		}
+	}
+
+	for key, owner := range emitted {
+		perTarget[key] = perTarget[owner]
+	}
+
+	plan := &templatePlan{order: order, references: references, perTarget: perTarget, emitted: emitted}
+	if passwordSpec.Document != nil {
+		plan.document, err = planDocument(passwordSpec, perTarget, emitted)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// planDocument determines the keys the document template refers to, and whether it is specific to each target
+func planDocument(passwordSpec *v1.SecretTemplate, perTarget map[string]bool, emitted map[string]string) (*documentPlan, error) {
+	template := passwordSpec.Document.Template
+	keys, all, err := templated.SelfReferences(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate document: %w", err)
+	}
+	if all {
+		keys = make([]string, 0, len(passwordSpec.Data))
+		for key := range passwordSpec.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+	refersToTarget, err := templated.ReferencesTarget(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate document: %w", err)
+	}
+
+	plan := &documentPlan{references: keys, perTarget: refersToTarget}
+	for _, key := range keys {
+		_, found := passwordSpec.Data[key]
+		if _, isEmitted := emitted[key]; !found && !isEmitted {
+			return nil, fmt.Errorf("document refers to unknown key %s", key)
+		}
+		plan.perTarget = plan.perTarget || perTarget[key]
+	}
+	return plan, nil
+}
+
+// mergeOverrides returns the spec with the items overridden for a target
+func mergeOverrides(passwordSpec *v1.SecretTemplate, overrides v1.SecretValueItems) *v1.SecretTemplate {
+	if len(overrides) == 0 {
+		return passwordSpec
+	}
+	merged := &v1.SecretTemplate{
+		Data:     make(v1.SecretValueItems, len(passwordSpec.Data)+len(overrides)),
+		Document: passwordSpec.Document,
+	}
+	for name, item := range passwordSpec.Data {
+		merged.Data[name] = item
+	}
+	for name, item := range overrides {
+		merged.Data[name] = item
+	}
+	return merged
+}
+
+// copyValues returns a shallow copy of the values
+func copyValues(values map[string][]byte) map[string][]byte {
+	data := make(map[string][]byte, len(values))
+	for key, value := range values {
+		data[key] = value
+	}
+	return data
+}
+
+func sortedKeys(items v1.SecretValueItems) []string {
+	keys := make([]string, 0, len(items))
+	for name := range items {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// missingKeyOption converts the missing key policy of the spec to the template option. Templates without a policy
+// keep the legacy behaviour of rendering missing keys as "<no value>".
+func missingKeyOption(policy v1.MissingKeyPolicy) templated.MissingKey {
+	switch policy {
+	case v1.MissingKeyError:
+		return templated.MissingKeyError
+	case v1.MissingKeyZero:
+		return templated.MissingKeyZero
+	}
+	return templated.MissingKeyDefault
+}
+
+// isTemplatedItem returns true if the value of the item is rendered from a template
+func isTemplatedItem(item *v1.SecretValueItemTemplate) bool {
+	if item.Value != "" || (item.Static != nil && item.Static.Value != "") {
+		return false
+	}
+	return item.Templated != nil
+}
+
+// isDerivedItem returns true if the value of the item is derived from the other keys, by a template, a hash or a
+// docker config, or sets several keys, as htpasswd values do. Derived values are rendered after all other values, in
+// dependency order.
+func isDerivedItem(item *v1.SecretValueItemTemplate) bool {
+	if item.Value != "" || (item.Static != nil && item.Static.Value != "") {
+		return false
+	}
+	return item.Templated != nil || item.Hashed != nil || item.Htpasswd != nil || item.DockerConfig != nil
+}
+
+// fetchTemplateInputs fetches the input secrets and config maps of a templated value
+// The values of the other keys are not included, they are set as .Self when rendering
+func fetchTemplateInputs(ctx context.Context, fetcher SecretFetcher, defaultNamespace string, spec *v1.TemplatedValueSpec) (templated.TemplateData, []v1.TemplateInputVersion, error) {
+	data := templated.TemplateData{
+		Ref:    map[string]string{},
+		Inputs: map[string]map[string]string{},
+	}
+	versions := []v1.TemplateInputVersion{}
+
+	if err := validateTemplateInputs(spec); err != nil {
+		return data, nil, err
+	}
+	if spec.InputSecretRef != nil {
+		values, version, err := fetchInputSecret(ctx, fetcher, defaultNamespace, spec.InputSecretRef)
+		if err != nil {
+			return data, nil, err
+		}
+		data.Ref = values
+		versions = append(versions, version)
+	}
+
+	aliases := make([]string, 0, len(spec.Inputs))
+	for alias := range spec.Inputs {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		values, version, err := fetchInput(ctx, fetcher, defaultNamespace, spec.Inputs[alias])
+		if err != nil {
+			return data, nil, fmt.Errorf("failed to fetch input %s: %w", alias, err)
+		}
+		data.Inputs[alias] = values
+		versions = append(versions, version)
+	}
+	return data, versions, nil
+}
+
+// validateTemplateInputs returns an error when a templated value without inputSecretRef or inputs refers to .Ref or
+// .Inputs, or does not refer to .Self, .Target or .GeneratedSecret either, so a template is never rendered against
+// inputs that do not exist
+func validateTemplateInputs(spec *v1.TemplatedValueSpec) error {
+	if spec.InputSecretRef != nil || len(spec.Inputs) != 0 {
+		return nil
+	}
+	usesInputs, err := templated.ReferencesInputs(spec.Template)
+	if err != nil {
+		return err
+	}
+	if usesInputs {
+		return fmt.Errorf("inputSecretRef or inputs is required for templated values that refer to .Ref or .Inputs")
+	}
+	keys, all, err := templated.SelfReferences(spec.Template)
+	if err != nil {
+		return err
+	}
+	usesTarget, err := templated.ReferencesTarget(spec.Template)
+	if err != nil {
+		return err
+	}
+	usesGeneratedSecret, err := templated.ReferencesGeneratedSecret(spec.Template)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 && !all && !usesTarget && !usesGeneratedSecret {
+		return fmt.Errorf("inputSecretRef, inputs or a reference to .Self, .Target or .GeneratedSecret is required for templated values")
+	}
+	return nil
+}
+
+// fetchInput fetches the secret or config map of a named input and returns its values for use in a template
+func fetchInput(ctx context.Context, fetcher SecretFetcher, defaultNamespace string, input v1.TemplateInput) (map[string]string, v1.TemplateInputVersion, error) {
+	switch {
+	case input.SecretRef != nil && input.ConfigMapRef != nil:
+		return nil, v1.TemplateInputVersion{}, fmt.Errorf("only one of secretRef or configMapRef can be set")
+	case input.SecretRef != nil:
+		return fetchInputSecret(ctx, fetcher, defaultNamespace, input.SecretRef)
+	case input.ConfigMapRef != nil:
+		return fetchInputConfigMap(ctx, fetcher, defaultNamespace, input.ConfigMapRef)
+	}
+	return nil, v1.TemplateInputVersion{}, fmt.Errorf("secretRef or configMapRef is required")
+}
+
+// fetchInputSecret fetches the referenced secret and returns its values for use in a template
+func fetchInputSecret(ctx context.Context, fetcher SecretFetcher, defaultNamespace string, ref *v1.SecretReference) (map[string]string, v1.TemplateInputVersion, error) {
 	// Determine the namespace to fetch from
-	namespace := spec.InputSecretRef.Namespace
+	namespace := ref.Namespace
 	if namespace == "" {
 		namespace = defaultNamespace
 	}
 
-	// Fetch the input secret
 	var secret corev1.Secret
 	err := fetcher.Get(ctx, types.NamespacedName{
-		Name:      spec.InputSecretRef.Name,
+		Name:      ref.Name,
 		Namespace: namespace,
 	}, &secret)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch input secret %s/%s: %w", namespace, spec.InputSecretRef.Name, err)
+		return nil, v1.TemplateInputVersion{}, fmt.Errorf("failed to fetch input secret %s/%s: %w", namespace, ref.Name, err)
+	}
+	version := v1.TemplateInputVersion{
+		Kind:            InputKindSecret,
+		Namespace:       namespace,
+		Name:            ref.Name,
+		ResourceVersion: secret.ResourceVersion,
+	}
+	return templated.NewInputValues(secret.Data), version, nil
+}
+
+// fetchInputConfigMap fetches the referenced config map and returns both its data and binaryData for use in a template
+func fetchInputConfigMap(ctx context.Context, fetcher SecretFetcher, defaultNamespace string, ref *v1.ConfigMapReference) (map[string]string, v1.TemplateInputVersion, error) {
+	// Determine the namespace to fetch from
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
 	}
 
-	// Render the template
-	result, err := templated.RenderTemplate(spec.Template, secret.Data)
+	var configMap corev1.ConfigMap
+	err := fetcher.Get(ctx, types.NamespacedName{
+		Name:      ref.Name,
+		Namespace: namespace,
+	}, &configMap)
 	if err != nil {
-		return nil, fmt.Errorf("failed to render template: %w", err)
+		return nil, v1.TemplateInputVersion{}, fmt.Errorf("failed to fetch input config map %s/%s: %w", namespace, ref.Name, err)
 	}
 
-	return result, nil
+	// Keys are unique across data and binaryData, this is enforced by the API server
+	values := templated.NewInputValues(configMap.BinaryData)
+	for key, value := range configMap.Data {
+		values[key] = value
+	}
+	version := v1.TemplateInputVersion{
+		Kind:            InputKindConfigMap,
+		Namespace:       namespace,
+		Name:            ref.Name,
+		ResourceVersion: configMap.ResourceVersion,
+	}
+	return values, version, nil
 }
 
-func getPasswordLength(item *v1.SecretValueItemTemplate) int {
+func getPasswordLength(random io.Reader, item *v1.SecretValueItemTemplate) (int, error) {
 	if item.Generated == nil {
-		return 0
+		return 0, nil
 	}
 	lengthOfPassword := item.Generated.Length
 	if item.Generated.MaxLength > 0 {
-		lengthOfPassword = uint32(getRandomNumberBetween(int(item.Generated.MinLength), int(item.Generated.MaxLength)))
+		length, err := getRandomNumberBetween(random, int(item.Generated.MinLength), int(item.Generated.MaxLength))
+		if err != nil {
+			return 0, err
+		}
+		lengthOfPassword = uint32(length)
 	} else {
 		lengthOfPassword = uint32(math.Max(float64(lengthOfPassword), float64(item.Generated.MinLength)))
 	}
-	return int(lengthOfPassword)
+	return int(lengthOfPassword), nil
 }
 
-func getNumberOfSymbols(item *v1.SecretValueItemTemplate) int {
-	if item.Generated == nil {
-		return 0
+func getRandomNumberBetween(random io.Reader, min int, max int) (int, error) {
+	low, high := numberRange(min, max)
+	n, err := entropy.Int(random, high-low+1)
+	if err != nil {
+		return 0, err
 	}
-	return int(math.Min(float64(getPasswordLength(item)), float64(getRandomNumberBetween(0, int(item.Generated.MaxSymbols)))))
-}
-
-func getNumberOfDigits(item *v1.SecretValueItemTemplate) int {
-	return int(math.Min(float64(getPasswordLength(item)), float64(getRandomNumberBetween(0, int(item.Generated.MaxDigits)))))
+	return low + n, nil
 }
 
-func getRandomNumberBetween(min int, max int) int {
+// numberRange returns the lowest and highest number getRandomNumberBetween returns
+func numberRange(min int, max int) (int, int) {
 	if max == 0 {
-		return 0
+		return 0, 0
 	} else if min >= max {
-		return max
+		return max, max
 	}
-
-	return min + int(rand.Int31n(int32(max-min)))
+	return min, max - 1
 }
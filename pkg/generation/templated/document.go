@@ -0,0 +1,202 @@
+package templated
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DocumentFormat is the format of a rendered document
+type DocumentFormat string
+
+const (
+	// DocumentFormatYAML parses the document as YAML, nested values are encoded as YAML
+	DocumentFormatYAML DocumentFormat = "yaml"
+	// DocumentFormatJSON parses the document as JSON, nested values are encoded as JSON
+	DocumentFormatJSON DocumentFormat = "json"
+)
+
+// ParseDocument splits a rendered document into secret data. The document must be a mapping, every top-level key
+// becomes a key of the secret. Strings are used as-is, numbers and booleans as written in the document, null as an
+// empty value, and nested lists and mappings are encoded in the format of the document.
+func ParseDocument(document []byte, format DocumentFormat) (map[string][]byte, error) {
+	switch format {
+	case DocumentFormatJSON:
+		return parseJSONDocument(document)
+	case DocumentFormatYAML, "":
+		return parseYAMLDocument(document)
+	}
+	return nil, &Error{Err: fmt.Errorf("unsupported document format %q", format)}
+}
+
+// parseJSONDocument walks the tokens of the document to find duplicate keys, of which unmarshalling into a map silently
+// keeps the last
+func parseJSONDocument(document []byte) (map[string][]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(document, &fields); err != nil {
+		return nil, &Error{Err: fmt.Errorf("failed to parse document, it must be a mapping of keys to values: %w", err)}
+	}
+	if fields == nil {
+		return map[string][]byte{}, nil
+	}
+
+	// The document is a valid mapping, so the first token opens it and the keys are strings
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	if _, err := decoder.Token(); err != nil {
+		return nil, &Error{Err: fmt.Errorf("failed to parse document: %w", err)}
+	}
+	data := make(map[string][]byte, len(fields))
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("failed to parse document: %w", err)}
+		}
+		key := token.(string)
+		var field json.RawMessage
+		if err := decoder.Decode(&field); err != nil {
+			return nil, &Error{Err: fmt.Errorf("failed to parse document key %q: %w", key, err)}
+		}
+		if err := validateDocumentKey(key); err != nil {
+			return nil, err
+		}
+		if _, exists := data[key]; exists {
+			return nil, &Error{Err: fmt.Errorf("document key %q is defined more than once", key)}
+		}
+		value, err := documentValue(field)
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("failed to encode document key %q: %w", key, err)}
+		}
+		data[key] = value
+	}
+	return data, nil
+}
+
+// parseYAMLDocument parses the document into YAML nodes rather than values, so scalars keep the text they were written
+// with. Converting them to values would apply the YAML 1.1 rules, which turn 0123 into 83, yes into true and large
+// integers into floats.
+func parseYAMLDocument(document []byte) (map[string][]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(document, &root); err != nil {
+		return nil, &Error{Err: fmt.Errorf("failed to parse document as YAML: %w", err)}
+	}
+	if root.Kind == 0 || len(root.Content) == 0 {
+		return map[string][]byte{}, nil
+	}
+	mapping := root.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, &Error{Err: fmt.Errorf("failed to parse document, it must be a mapping of keys to values")}
+	}
+
+	data := make(map[string][]byte, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, valueNode := mapping.Content[i], mapping.Content[i+1]
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, &Error{Err: fmt.Errorf("failed to parse document, keys must be strings")}
+		}
+		key := keyNode.Value
+		if err := validateDocumentKey(key); err != nil {
+			return nil, err
+		}
+		if _, exists := data[key]; exists {
+			return nil, &Error{Err: fmt.Errorf("document key %q is defined more than once", key)}
+		}
+		value, err := yamlValue(valueNode)
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("failed to encode document key %q: %w", key, err)}
+		}
+		data[key] = value
+	}
+	return data, nil
+}
+
+func validateDocumentKey(key string) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) != 0 {
+		return &Error{Err: fmt.Errorf("document key %q is not a valid secret key: %s", key, strings.Join(errs, ", "))}
+	}
+	return nil
+}
+
+// maxDocumentNodes bounds the number of nodes a nested YAML value may expand to through aliases
+const maxDocumentNodes = 100000
+
+// yamlValue converts a single value of a YAML document to secret data
+func yamlValue(node *yaml.Node) ([]byte, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode {
+		if node.ShortTag() == "!!null" {
+			return []byte{}, nil
+		}
+		return []byte(node.Value), nil
+	}
+
+	// Aliases are expanded, so the nested value is a complete YAML document by itself
+	budget := maxDocumentNodes
+	expanded, err := expandAliases(node, &budget)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	encoder.CompactSeqIndent()
+	if err := encoder.Encode(expanded); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// expandAliases returns a copy of the node in block style, with every alias replaced by the node it refers to
+func expandAliases(node *yaml.Node, budget *int) (*yaml.Node, error) {
+	if *budget--; *budget < 0 {
+		return nil, fmt.Errorf("value expands to more than %d nodes", maxDocumentNodes)
+	}
+	if node.Kind == yaml.AliasNode {
+		return expandAliases(node.Alias, budget)
+	}
+	expanded := *node
+	expanded.Anchor = ""
+	if node.Kind != yaml.ScalarNode {
+		// Nested values are written in block style, as the rest of the YAML the operator writes
+		expanded.Style &^= yaml.FlowStyle
+	}
+	expanded.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		var err error
+		if expanded.Content[i], err = expandAliases(child, budget); err != nil {
+			return nil, err
+		}
+	}
+	return &expanded, nil
+}
+
+// documentValue converts a single value of a JSON document to secret data
+func documentValue(field json.RawMessage) ([]byte, error) {
+	trimmed := bytes.TrimSpace(field)
+	switch {
+	case len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")):
+		return []byte{}, nil
+	case trimmed[0] == '"':
+		var value string
+		if err := json.Unmarshal(trimmed, &value); err != nil {
+			return nil, err
+		}
+		return []byte(value), nil
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, trimmed); err != nil {
+			return nil, err
+		}
+		return compact.Bytes(), nil
+	}
+	// Numbers and booleans
+	return trimmed, nil
+}
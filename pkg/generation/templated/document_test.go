@@ -0,0 +1,124 @@
+package templated
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDocument(t *testing.T) {
+	tests := []struct {
+		name        string
+		document    string
+		format      DocumentFormat
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name:     "yaml scalars",
+			document: "username: admin\nport: 5432\ntls: true\nempty: null\n",
+			format:   DocumentFormatYAML,
+			expected: map[string]string{"username": "admin", "port": "5432", "tls": "true", "empty": ""},
+		},
+		{
+			name:     "yaml scalars are kept as written",
+			document: "pin: 0123\nhex: 0x1F\nenabled: yes\nexponent: 1e3\nlarge: 12345678901234567890123\noctal: 0o17\nquoted: '0123'\ntilde: ~\n",
+			format:   DocumentFormatYAML,
+			expected: map[string]string{
+				"pin": "0123", "hex": "0x1F", "enabled": "yes", "exponent": "1e3", "large": "12345678901234567890123",
+				"octal": "0o17", "quoted": "0123", "tilde": "",
+			},
+		},
+		{
+			name:     "yaml nested scalars are kept as written",
+			document: "config.yaml:\n  pin: 0123\n  flags: [yes, 0x1F]\n",
+			format:   DocumentFormatYAML,
+			expected: map[string]string{"config.yaml": "pin: 0123\nflags:\n- yes\n- 0x1F\n"},
+		},
+		{
+			name:     "yaml aliases are expanded",
+			document: "base: &base\n  host: db\ncopy: *base\nname: &name admin\nuser: *name\n",
+			format:   DocumentFormatYAML,
+			expected: map[string]string{"base": "host: db\n", "copy": "host: db\n", "name": "admin", "user": "admin"},
+		},
+		{
+			name:        "yaml keys must be unique",
+			document:    "user: admin\nuser: root\n",
+			format:      DocumentFormatYAML,
+			expectError: true,
+		},
+		{
+			name:        "yaml aliases are bounded",
+			document:    "a: &a [x, x, x, x, x, x, x, x, x, x]\nb: &b [*a, *a, *a, *a, *a, *a, *a, *a, *a, *a]\nc: &c [*b, *b, *b, *b, *b, *b, *b, *b, *b, *b]\nd: &d [*c, *c, *c, *c, *c, *c, *c, *c, *c, *c]\ne: [*d, *d, *d, *d, *d, *d, *d, *d, *d, *d]\n",
+			format:      DocumentFormatYAML,
+			expectError: true,
+		},
+		{
+			name:     "yaml nested values are encoded as yaml",
+			document: "config.yaml: \n  host: db\n  ports: [5432]\nusers:\n- admin\n",
+			format:   DocumentFormatYAML,
+			expected: map[string]string{"config.yaml": "host: db\nports:\n- 5432\n", "users": "- admin\n"},
+		},
+		{
+			name:     "json nested values are encoded as json",
+			document: `{"config.json": {"host": "db", "port": 5432}, "user": "admin"}`,
+			format:   DocumentFormatJSON,
+			expected: map[string]string{"config.json": `{"host":"db","port":5432}`, "user": "admin"},
+		},
+		{
+			name:        "json keys must be unique",
+			document:    `{"user": "admin", "user": "root"}`,
+			format:      DocumentFormatJSON,
+			expectError: true,
+		},
+		{
+			name:        "json document must be a mapping",
+			document:    `["admin", "root"]`,
+			format:      DocumentFormatJSON,
+			expectError: true,
+		},
+		{
+			name:     "empty document",
+			document: "",
+			format:   DocumentFormatYAML,
+			expected: map[string]string{},
+		},
+		{
+			name:        "json format does not accept yaml",
+			document:    "user: admin",
+			format:      DocumentFormatJSON,
+			expectError: true,
+		},
+		{
+			name:        "document must be a mapping",
+			document:    "- admin\n- root\n",
+			format:      DocumentFormatYAML,
+			expectError: true,
+		},
+		{
+			name:        "keys must be valid secret keys",
+			document:    "db/user: admin\n",
+			format:      DocumentFormatYAML,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseDocument([]byte(tt.document), tt.format)
+
+			if tt.expectError {
+				var templateErr *Error
+				require.ErrorAs(t, err, &templateErr)
+				return
+			}
+			require.NoError(t, err)
+			values := map[string]string{}
+			for key, value := range data {
+				values[key] = string(value)
+			}
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package templated
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The functions in this file serialise a map of values into a configuration file format, with the quoting and
+// escaping of that format. Keys are always written in sorted order, so the output only changes when a value does.
+// Maps can be built from .Ref, .Self or .Inputs directly, or with dict, e.g.
+// {{ dict "DB_USER" "app" "DB_PASSWORD" .Self.password | toEnv }}
+
+// dict builds a map from alternating keys and values
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected an even number of arguments, got %d", len(pairs))
+	}
+	values := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v must be a string", pairs[i])
+		}
+		values[key] = pairs[i+1]
+	}
+	return values, nil
+}
+
+// list builds a list from its arguments, e.g. for arrays in toToml
+func list(items ...any) []any {
+	return items
+}
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var envSafeValuePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// toEnv serialises the values as a .env file. Values that contain anything but safe characters are double quoted,
+// with backslashes, quotes, dollar signs, backticks and line breaks escaped.
+func toEnv(value any) (string, error) {
+	values, err := formatMap("toEnv", value)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, key := range sortedMapKeys(values) {
+		if !envKeyPattern.MatchString(key) {
+			return "", fmt.Errorf("toEnv: %q is not a valid variable name", key)
+		}
+		scalar, err := formatScalar("toEnv", key, values[key])
+		if err != nil {
+			return "", err
+		}
+		if !envSafeValuePattern.MatchString(scalar) {
+			scalar = `"` + envEscaper.Replace(scalar) + `"`
+		}
+		fmt.Fprintf(&out, "%s=%s\n", key, scalar)
+	}
+	return out.String(), nil
+}
+
+var envEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+
+// toProperties serialises the values as a Java .properties file. Separators and comment characters in keys, leading
+// whitespace of values and line breaks are escaped, and characters outside of ISO 8859-1 are written as \uXXXX.
+func toProperties(value any) (string, error) {
+	values, err := formatMap("toProperties", value)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, key := range sortedMapKeys(values) {
+		scalar, err := formatScalar("toProperties", key, values[key])
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&out, "%s=%s\n", escapeProperty(key, true), escapeProperty(scalar, false))
+	}
+	return out.String(), nil
+}
+
+func escapeProperty(value string, isKey bool) string {
+	var out strings.Builder
+	for i, r := range value {
+		switch {
+		case r == '\\':
+			out.WriteString(`\\`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r == '\f':
+			out.WriteString(`\f`)
+		case r == ' ' && (isKey || i == 0):
+			out.WriteString(`\ `)
+		case isKey && strings.ContainsRune("=:#!", r), !isKey && i == 0 && strings.ContainsRune("#!", r):
+			out.WriteRune('\\')
+			out.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16Units(r) {
+				fmt.Fprintf(&out, `\u%04X`, unit)
+			}
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// utf16Units returns the UTF-16 code units of the rune, as used by \u escapes
+func utf16Units(r rune) []rune {
+	if r < 0x10000 {
+		return []rune{r}
+	}
+	r -= 0x10000
+	return []rune{0xD800 + (r>>10)&0x3FF, 0xDC00 + r&0x3FF}
+}
+
+var iniSafeValuePattern = regexp.MustCompile(`^[^\s"'\\;#=\[\]]([^"'\\;#\n\r]*[^\s"'\\;#])?$`)
+
+// toIni serialises the values as an INI file. Nested maps are written as sections after the top-level keys.
+// Values that are empty, have surrounding whitespace or contain quotes, comment characters or line breaks are double
+// quoted, with backslashes, quotes and line breaks escaped.
+func toIni(value any) (string, error) {
+	values, err := formatMap("toIni", value)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	sections := []string{}
+	for _, key := range sortedMapKeys(values) {
+		if isMap(values[key]) {
+			sections = append(sections, key)
+			continue
+		}
+		if err := writeIniKey(&out, key, values[key]); err != nil {
+			return "", err
+		}
+	}
+	for _, section := range sections {
+		if strings.ContainsAny(section, "[]\n\r") {
+			return "", fmt.Errorf("toIni: %q is not a valid section name", section)
+		}
+		sectionValues, err := formatMap("toIni", values[section])
+		if err != nil {
+			return "", err
+		}
+		if out.Len() != 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "[%s]\n", section)
+		for _, key := range sortedMapKeys(sectionValues) {
+			if err := writeIniKey(&out, key, sectionValues[key]); err != nil {
+				return "", err
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+func writeIniKey(out *strings.Builder, key string, value any) error {
+	if key == "" || strings.ContainsAny(key, "=;#[]\"\n\r") || strings.TrimSpace(key) != key {
+		return fmt.Errorf("toIni: %q is not a valid key", key)
+	}
+	scalar, err := formatScalar("toIni", key, value)
+	if err != nil {
+		return err
+	}
+	if !iniSafeValuePattern.MatchString(scalar) {
+		scalar = `"` + iniEscaper.Replace(scalar) + `"`
+	}
+	fmt.Fprintf(out, "%s = %s\n", key, scalar)
+	return nil
+}
+
+var iniEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+var tomlBareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// toToml serialises the values as a TOML document. Nested maps are written as tables, lists as arrays, and strings
+// as basic strings.
+func toToml(value any) (string, error) {
+	values, err := formatMap("toToml", value)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := writeTomlTable(&out, nil, values); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func writeTomlTable(out *strings.Builder, path []string, values map[string]any) error {
+	tables := []string{}
+	for _, key := range sortedMapKeys(values) {
+		if isMap(values[key]) {
+			tables = append(tables, key)
+			continue
+		}
+		encoded, err := tomlValue(key, values[key])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s = %s\n", tomlKey(key), encoded)
+	}
+	for _, key := range tables {
+		table, err := formatMap("toToml", values[key])
+		if err != nil {
+			return err
+		}
+		tablePath := append(append([]string{}, path...), tomlKey(key))
+		if out.Len() != 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(out, "[%s]\n", strings.Join(tablePath, "."))
+		if err := writeTomlTable(out, tablePath, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlKey(key string) string {
+	if tomlBareKeyPattern.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlValue(key string, value any) (string, error) {
+	if b, ok := value.([]byte); ok {
+		return tomlString(string(b)), nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return tomlString(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := tomlValue(key, v.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("toToml: value of %q has unsupported type %T", key, value)
+}
+
+// tomlString encodes a TOML basic string, escaping quotes, backslashes and control characters
+func tomlString(value string) string {
+	var out strings.Builder
+	out.WriteRune('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+				fmt.Fprintf(&out, `\u%04X`, r)
+				continue
+			}
+			out.WriteRune(r)
+		}
+	}
+	out.WriteRune('"')
+	return out.String()
+}
+
+// formatMap converts a map with string keys, such as .Ref or the result of dict, to a map of values
+func formatMap(function string, value any) (map[string]any, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("%s: expected a map with string keys, got %T", function, value)
+	}
+	values := make(map[string]any, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		values[iter.Key().String()] = iter.Value().Interface()
+	}
+	return values, nil
+}
+
+// formatScalar converts a single value to a string, nested maps and lists are not supported
+func formatScalar(function, key string, value any) (string, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "", nil
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		if b, ok := value.([]byte); ok {
+			return string(b), nil
+		}
+		return "", fmt.Errorf("%s: value of %q must be a scalar, got %T", function, key, value)
+	}
+	return fmt.Sprint(value), nil
+}
+
+func isMap(value any) bool {
+	return reflect.ValueOf(value).Kind() == reflect.Map
+}
+
+func sortedMapKeys(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
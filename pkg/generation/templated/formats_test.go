@@ -0,0 +1,94 @@
+package templated
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatFunctions(t *testing.T) {
+	secretData := map[string][]byte{
+		"user":     []byte("app"),
+		"password": []byte(`p@ss "w$rd"\` + "\n#1"),
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "toEnv quotes values with special characters",
+			template: `{{ dict "DB_USER" .Ref.user "DB_PASSWORD" .Ref.password "DB_URL" "postgres://db:5432/app" | toEnv }}`,
+			expected: "DB_PASSWORD=\"p@ss \\\"w\\$rd\\\"\\\\\\n#1\"\nDB_URL=postgres://db:5432/app\nDB_USER=app\n",
+		},
+		{
+			name:     "toEnv quotes values with whitespace",
+			template: `{{ dict "EMPTY" "" "SPACED" " a b " | toEnv }}`,
+			expected: "EMPTY=\nSPACED=\" a b \"\n",
+		},
+		{
+			name:        "toEnv rejects invalid variable names",
+			template:    `{{ dict "db.user" .Ref.user | toEnv }}`,
+			expectError: "not a valid variable name",
+		},
+		{
+			name:     "toProperties escapes keys and values",
+			template: `{{ dict "db.user" .Ref.user "db.password" .Ref.password "key with=sep" " lead" "unicode" "é€" | toProperties }}`,
+			expected: "db.password=p@ss \"w$rd\"\\\\\\n#1\n" +
+				"db.user=app\n" +
+				"key\\ with\\=sep=\\ lead\n" +
+				"unicode=\\u00E9\\u20AC\n",
+		},
+		{
+			name:     "toIni writes sections after top-level keys",
+			template: `{{ dict "database" (dict "password" .Ref.password "user" .Ref.user) "name" "app server" | toIni }}`,
+			expected: "name = app server\n\n[database]\npassword = \"p@ss \\\"w$rd\\\"\\\\\\n#1\"\nuser = app\n",
+		},
+		{
+			name:        "toIni rejects nested sections",
+			template:    `{{ dict "a" (dict "b" (dict "c" "d")) | toIni }}`,
+			expectError: "must be a scalar",
+		},
+		{
+			name:     "toToml writes tables and basic strings",
+			template: `{{ dict "database" (dict "password" .Ref.password "port" 5432 "tls" true) "name" "app" "hosts" (list "a" "b") "dotted.key" "x" | toToml }}`,
+			expected: "\"dotted.key\" = \"x\"\nhosts = [\"a\", \"b\"]\nname = \"app\"\n\n[database]\npassword = \"p@ss \\\"w$rd\\\"\\\\\\n#1\"\nport = 5432\ntls = true\n",
+		},
+		{
+			name:     "toToml writes nested tables",
+			template: `{{ dict "a" (dict "b" (dict "c" "d")) | toToml }}`,
+			expected: "[a]\n\n[a.b]\nc = \"d\"\n",
+		},
+		{
+			name:     "formats accept the values of a secret",
+			template: `{{ .Ref | toProperties }}`,
+			expected: "password=p@ss \"w$rd\"\\\\\\n#1\nuser=app\n",
+		},
+		{
+			name:        "dict requires pairs",
+			template:    `{{ dict "a" }}`,
+			expectError: "even number of arguments",
+		},
+		{
+			name:        "formats require a map",
+			template:    `{{ .Ref.user | toEnv }}`,
+			expectError: "expected a map",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenderTemplate(tt.template, secretData)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package templated
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/crypto/bcrypt"
+	"sigs.k8s.io/yaml"
+)
+
+// FuncMap returns the functions that are available in secret templates.
+// Functions follow the pipeline convention, the value being operated on is always the last argument.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		// Encoding
+		"b64enc": b64enc,
+		"b64dec": b64dec,
+		"hex":    hexenc,
+
+		// Hashing
+		"sha256": sha256sum,
+		"sha512": sha512sum,
+		"hmac":   hmacsum,
+
+		// Password hashes
+		"bcrypt":   bcryptHash,
+		"htpasswd": htpasswd,
+
+		// Escaping
+		"urlquery": url.QueryEscape,
+		"quote":    strconv.Quote,
+
+		// Serialisation
+		"toJson":       toJSON,
+		"toYaml":       toYAML,
+		"toEnv":        toEnv,
+		"toProperties": toProperties,
+		"toIni":        toIni,
+		"toToml":       toToml,
+
+		// Helpers
+		"dict":     dict,
+		"list":     list,
+		"default":  defaultValue,
+		"required": required,
+		"trim":     strings.TrimSpace,
+		"indent":   indent,
+	}
+}
+
+func b64enc(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func b64dec(value string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func hexenc(value string) string {
+	return hex.EncodeToString([]byte(value))
+}
+
+func sha256sum(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha512sum(value string) string {
+	sum := sha512.Sum512([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacsum returns the hex encoded HMAC of the message, e.g. {{ .Ref.message | hmac "sha256" .Ref.key }}
+func hmacsum(algorithm, key, message string) (string, error) {
+	var newHash func() hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		newHash = sha256.New
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return "", fmt.Errorf("hmac: unsupported algorithm %q, must be sha256 or sha512", algorithm)
+	}
+	mac := hmac.New(newHash, []byte(key))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func bcryptHash(value string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(value), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt: %w", err)
+	}
+	return string(hashed), nil
+}
+
+// htpasswd returns a htpasswd line for the user using a bcrypt hash, e.g. {{ htpasswd "admin" .Ref.password }}
+func htpasswd(username, password string) (string, error) {
+	if strings.Contains(username, ":") {
+		return "", fmt.Errorf("htpasswd: username %q must not contain a colon", username)
+	}
+	hashed, err := bcryptHash(password)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", username, hashed), nil
+}
+
+func toJSON(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func toYAML(value any) (string, error) {
+	encoded, err := yaml.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimSuffix(string(encoded), "\n"), nil
+}
+
+// defaultValue returns the given value, or the default if the value is empty, e.g. {{ .Ref.port | default "5432" }}
+func defaultValue(defaultVal any, given ...any) any {
+	if len(given) == 0 || isEmpty(given[0]) {
+		return defaultVal
+	}
+	return given[0]
+}
+
+// required fails the template with the message if the value is empty, e.g. {{ .Ref.host | required "host is required" }}
+func required(message string, value any) (any, error) {
+	if isEmpty(value) {
+		return nil, fmt.Errorf("%s", message)
+	}
+	return value, nil
+}
+
+func indent(spaces int, value string) string {
+	padding := strings.Repeat(" ", spaces)
+	return padding + strings.ReplaceAll(value, "\n", "\n"+padding)
+}
+
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Array, reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
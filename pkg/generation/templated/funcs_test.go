@@ -0,0 +1,179 @@
+package templated
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestTemplateFunctions(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		secretData  map[string][]byte
+		expected    string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:       "b64enc",
+			template:   "{{ .Ref.value | b64enc }}",
+			secretData: map[string][]byte{"value": []byte("hello")},
+			expected:   "aGVsbG8=",
+		},
+		{
+			name:       "b64dec",
+			template:   "{{ .Ref.value | b64dec }}",
+			secretData: map[string][]byte{"value": []byte("aGVsbG8=")},
+			expected:   "hello",
+		},
+		{
+			name:        "b64dec with invalid input",
+			template:    "{{ .Ref.value | b64dec }}",
+			secretData:  map[string][]byte{"value": []byte("not base64!")},
+			expectError: true,
+			errorMsg:    "b64dec",
+		},
+		{
+			name:       "hex",
+			template:   "{{ .Ref.value | hex }}",
+			secretData: map[string][]byte{"value": []byte("hi")},
+			expected:   "6869",
+		},
+		{
+			name:       "sha256",
+			template:   "{{ .Ref.value | sha256 }}",
+			secretData: map[string][]byte{"value": []byte("hello")},
+			expected:   "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		{
+			name:       "sha512",
+			template:   "{{ .Ref.value | sha512 }}",
+			secretData: map[string][]byte{"value": []byte("hello")},
+			expected:   "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+		},
+		{
+			name:       "hmac sha256",
+			template:   `{{ .Ref.message | hmac "sha256" .Ref.key }}`,
+			secretData: map[string][]byte{"key": []byte("key"), "message": []byte("The quick brown fox jumps over the lazy dog")},
+			expected:   "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		},
+		{
+			name:        "hmac with unsupported algorithm",
+			template:    `{{ .Ref.message | hmac "md5" .Ref.key }}`,
+			secretData:  map[string][]byte{"key": []byte("key"), "message": []byte("message")},
+			expectError: true,
+			errorMsg:    "unsupported algorithm",
+		},
+		{
+			name:       "urlquery escapes a password for a connection string",
+			template:   "postgres://app:{{ .Ref.password | urlquery }}@db:5432/app",
+			secretData: map[string][]byte{"password": []byte("p@ss/w:rd&")},
+			expected:   "postgres://app:p%40ss%2Fw%3Ard%26@db:5432/app",
+		},
+		{
+			name:       "quote",
+			template:   "{{ .Ref.value | quote }}",
+			secretData: map[string][]byte{"value": []byte(`say "hi"`)},
+			expected:   `"say \"hi\""`,
+		},
+		{
+			name:       "toJson",
+			template:   "{{ .Ref | toJson }}",
+			secretData: map[string][]byte{"user": []byte("admin"), "password": []byte("secret")},
+			expected:   `{"password":"secret","user":"admin"}`,
+		},
+		{
+			name:       "toYaml",
+			template:   "{{ .Ref | toYaml }}",
+			secretData: map[string][]byte{"user": []byte("admin"), "password": []byte("secret")},
+			expected:   "password: secret\nuser: admin",
+		},
+		{
+			name:       "default with missing value",
+			template:   `{{ .Ref.port | default "5432" }}`,
+			secretData: map[string][]byte{},
+			expected:   "5432",
+		},
+		{
+			name:       "default with empty value",
+			template:   `{{ .Ref.port | default "5432" }}`,
+			secretData: map[string][]byte{"port": []byte("")},
+			expected:   "5432",
+		},
+		{
+			name:       "default with value set",
+			template:   `{{ .Ref.port | default "5432" }}`,
+			secretData: map[string][]byte{"port": []byte("6432")},
+			expected:   "6432",
+		},
+		{
+			name:       "required with value set",
+			template:   `{{ .Ref.host | required "host is required" }}`,
+			secretData: map[string][]byte{"host": []byte("db")},
+			expected:   "db",
+		},
+		{
+			name:        "required with missing value",
+			template:    `{{ .Ref.host | required "host is required" }}`,
+			secretData:  map[string][]byte{},
+			expectError: true,
+			errorMsg:    "host is required",
+		},
+		{
+			name:       "trim",
+			template:   "{{ .Ref.value | trim }}",
+			secretData: map[string][]byte{"value": []byte("  padded\n")},
+			expected:   "padded",
+		},
+		{
+			name:       "indent",
+			template:   "key:\n{{ .Ref.value | indent 2 }}",
+			secretData: map[string][]byte{"value": []byte("a: 1\nb: 2")},
+			expected:   "key:\n  a: 1\n  b: 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenderTemplate(tt.template, tt.secretData)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, string(result))
+			}
+		})
+	}
+}
+
+func TestTemplateFunctionsPasswordHashes(t *testing.T) {
+	secretData := map[string][]byte{"password": []byte("secret123")}
+
+	t.Run("bcrypt", func(t *testing.T) {
+		result, err := RenderTemplate("{{ .Ref.password | bcrypt }}", secretData)
+		require.NoError(t, err)
+		assert.NoError(t, bcrypt.CompareHashAndPassword(result, []byte("secret123")))
+	})
+
+	t.Run("htpasswd", func(t *testing.T) {
+		result, err := RenderTemplate(`{{ htpasswd "admin" .Ref.password }}`, secretData)
+		require.NoError(t, err)
+
+		username, hash, found := strings.Cut(string(result), ":")
+		require.True(t, found)
+		assert.Equal(t, "admin", username)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret123")))
+	})
+
+	t.Run("htpasswd rejects a colon in the username", func(t *testing.T) {
+		_, err := RenderTemplate(`{{ htpasswd "ad:min" .Ref.password }}`, secretData)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must not contain a colon")
+	})
+}
@@ -0,0 +1,185 @@
+package templated
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	// selfField is the name of the template data field that holds the sibling values of the same secret
+	selfField = "Self"
+	// targetField is the name of the template data field that describes the target secret
+	targetField = "Target"
+	// generatedSecretField is the name of the template data field that describes the GeneratedSecret
+	generatedSecretField = "GeneratedSecret"
+	// refField and inputsField are the names of the template data fields that hold the values of the input secrets
+	// and config maps
+	refField    = "Ref"
+	inputsField = "Inputs"
+)
+
+// SelfReferences returns the sibling keys a template refers to through .Self, e.g. {{ .Self.password }} or
+// {{ index .Self "password" }}. When .Self is used as a whole, e.g. {{ toJson .Self }}, all is true.
+func SelfReferences(templateStr string) (keys []string, all bool, err error) {
+	refs, err := findReferences(templateStr, selfField)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for key := range refs.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, refs.all, nil
+}
+
+// ReferencesTarget returns true if the template refers to .Target, so it has to be rendered for each target separately
+func ReferencesTarget(templateStr string) (bool, error) {
+	refs, err := findReferences(templateStr, targetField)
+	if err != nil {
+		return false, err
+	}
+	return refs.all || len(refs.keys) != 0, nil
+}
+
+// ReferencesInputs returns true if the template refers to its input secrets or config maps through .Ref or .Inputs
+func ReferencesInputs(templateStr string) (bool, error) {
+	return referencesAny(templateStr, refField, inputsField)
+}
+
+// ReferencesGeneratedSecret returns true if the template refers to .GeneratedSecret
+func ReferencesGeneratedSecret(templateStr string) (bool, error) {
+	return referencesAny(templateStr, generatedSecretField)
+}
+
+// referencesAny returns true if the template refers to any of the given fields of the template data
+func referencesAny(templateStr string, fields ...string) (bool, error) {
+	for _, field := range fields {
+		refs, err := findReferences(templateStr, field)
+		if err != nil {
+			return false, err
+		}
+		if refs.all || len(refs.keys) != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findReferences returns the references of the template to the given field of the template data
+func findReferences(templateStr string, field string) (*fieldReferences, error) {
+	tmpl, err := template.New("secret").Funcs(FuncMap()).Parse(templateStr)
+	if err != nil {
+		return nil, &Error{Err: fmt.Errorf("failed to parse template: %w", err)}
+	}
+
+	refs := &fieldReferences{field: field, keys: map[string]struct{}{}}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			refs.walk(t.Tree.Root)
+		}
+	}
+	return refs, nil
+}
+
+// fieldReferences collects the references to a single field of the template data
+type fieldReferences struct {
+	field string
+	keys  map[string]struct{}
+	all   bool
+}
+
+func (r *fieldReferences) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			r.walk(child)
+		}
+	case *parse.ActionNode:
+		r.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			r.walk(cmd)
+		}
+	case *parse.CommandNode:
+		if key, ok := indexedKey(n, r.field); ok {
+			r.keys[key] = struct{}{}
+			for _, arg := range n.Args[3:] {
+				r.walk(arg)
+			}
+			return
+		}
+		for _, arg := range n.Args {
+			r.walk(arg)
+		}
+	case *parse.IfNode:
+		r.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		r.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		r.walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		r.walk(n.Pipe)
+	case *parse.ChainNode:
+		r.walk(n.Node)
+	case *parse.FieldNode:
+		r.addField(n.Ident)
+	case *parse.VariableNode:
+		// $.Field.key refers to the root data from within range and with blocks
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			r.addField(n.Ident[1:])
+		}
+	}
+}
+
+func (r *fieldReferences) walkBranch(n *parse.BranchNode) {
+	r.walk(n.Pipe)
+	r.walk(n.List)
+	r.walk(n.ElseList)
+}
+
+func (r *fieldReferences) addField(ident []string) {
+	if len(ident) == 0 || ident[0] != r.field {
+		return
+	}
+	if len(ident) == 1 {
+		r.all = true
+		return
+	}
+	r.keys[ident[1]] = struct{}{}
+}
+
+// indexedKey returns the key of an {{ index .Field "key" }} command for the given field
+func indexedKey(cmd *parse.CommandNode, field string) (string, bool) {
+	if len(cmd.Args) < 3 {
+		return "", false
+	}
+	if fn, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || fn.Ident != "index" {
+		return "", false
+	}
+	var ident []string
+	switch arg := cmd.Args[1].(type) {
+	case *parse.FieldNode:
+		ident = arg.Ident
+	case *parse.VariableNode:
+		if len(arg.Ident) > 1 && arg.Ident[0] == "$" {
+			ident = arg.Ident[1:]
+		}
+	}
+	if len(ident) != 1 || ident[0] != field {
+		return "", false
+	}
+	key, ok := cmd.Args[2].(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+	return key.Text, true
+}
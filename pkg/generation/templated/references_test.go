@@ -0,0 +1,161 @@
+package templated
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfReferences(t *testing.T) {
+	tests := []struct {
+		name         string
+		template     string
+		expectedKeys []string
+		expectedAll  bool
+		expectError  bool
+	}{
+		{
+			name:     "no references",
+			template: "{{ .Ref.password }}",
+		},
+		{
+			name:         "field reference",
+			template:     "{{ .Self.password }}",
+			expectedKeys: []string{"password"},
+		},
+		{
+			name:         "references in a pipeline and conditionals",
+			template:     "{{ if .Self.enabled }}{{ .Self.password | urlquery }}{{ else }}{{ .Self.fallback }}{{ end }}",
+			expectedKeys: []string{"enabled", "fallback", "password"},
+		},
+		{
+			name:         "index reference",
+			template:     `{{ index .Self "config.yaml" }}`,
+			expectedKeys: []string{"config.yaml"},
+		},
+		{
+			name:         "root variable reference within range",
+			template:     "{{ range .Ref }}{{ $.Self.password }}{{ end }}",
+			expectedKeys: []string{"password"},
+		},
+		{
+			name:        "whole of self",
+			template:    "{{ toJson .Self }}",
+			expectedAll: true,
+		},
+		{
+			name:        "invalid template",
+			template:    "{{ .Self.password",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, all, err := SelfReferences(tt.template)
+
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedKeys, keys)
+			assert.Equal(t, tt.expectedAll, all)
+		})
+	}
+}
+
+func TestReferencesTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		expected    bool
+		expectError bool
+	}{
+		{
+			name:     "no reference",
+			template: "{{ .Self.password }}@{{ .GeneratedSecret.Namespace }}",
+		},
+		{
+			name:     "field reference",
+			template: "db.{{ .Target.Namespace }}.svc",
+			expected: true,
+		},
+		{
+			name:     "label reference",
+			template: `{{ index .Target.Labels "env" | default "dev" }}`,
+			expected: true,
+		},
+		{
+			name:     "whole of target",
+			template: "{{ toJson .Target }}",
+			expected: true,
+		},
+		{
+			name:        "invalid template",
+			template:    "{{ .Target.Name",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := ReferencesTarget(tt.template)
+
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, found)
+		})
+	}
+}
+
+func TestReferencesInputs(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		expected    bool
+		expectError bool
+	}{
+		{
+			name:     "no reference",
+			template: "{{ .Self.password }}@{{ .Target.Namespace }}",
+		},
+		{
+			name:     "ref reference",
+			template: "Value: {{ .Ref.data }}",
+			expected: true,
+		},
+		{
+			name:     "named input reference",
+			template: `{{ index .Inputs "db-creds" "password" }}`,
+			expected: true,
+		},
+		{
+			name:     "whole of ref within range",
+			template: "{{ range $key, $value := $.Ref }}{{ $key }}{{ end }}",
+			expected: true,
+		},
+		{
+			name:        "invalid template",
+			template:    "{{ .Ref.data",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := ReferencesInputs(tt.template)
+
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, found)
+		})
+	}
+}
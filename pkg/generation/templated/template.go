@@ -2,42 +2,244 @@ package templated
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"text/template"
+	"text/template/parse"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // TemplateData represents the data structure available in templates
 type TemplateData struct {
+	// Ref holds the values of the single input secret
 	Ref map[string]string
+	// Inputs holds the values of the named input secrets and config maps, by alias
+	Inputs map[string]map[string]string
+	// Self holds the values of the other keys of the same secret
+	Self map[string]string
+	// Target describes the target secret the template is rendered for
+	Target Target
+	// GeneratedSecret identifies the GeneratedSecret the template belongs to
+	GeneratedSecret Object
+}
+
+// Target describes the target secret a template is rendered for
+type Target struct {
+	// Namespace of the target secret
+	Namespace string
+	// Name of the target secret
+	Name string
+	// Labels of the target namespace
+	Labels map[string]string
+	// Annotations of the target namespace
+	Annotations map[string]string
+}
+
+// Object identifies a Kubernetes object by name and namespace
+type Object struct {
+	Name      string
+	Namespace string
+}
+
+// NewInputValues converts secret data to the string values used in templates
+func NewInputValues(secretData map[string][]byte) map[string]string {
+	values := make(map[string]string, len(secretData))
+	for key, value := range secretData {
+		values[key] = string(value)
+	}
+	return values
+}
+
+// MissingKey controls how a template behaves when it refers to a key that does not exist
+type MissingKey string
+
+const (
+	// MissingKeyDefault renders missing keys as "<no value>", this is the default of Go templates
+	MissingKeyDefault MissingKey = "default"
+	// MissingKeyZero renders missing keys as an empty string
+	MissingKeyZero MissingKey = "zero"
+	// MissingKeyError fails the template when it refers to a missing key
+	MissingKeyError MissingKey = "error"
+)
+
+const (
+	// DefaultTimeout is the default time a template may take to execute
+	DefaultTimeout = time.Second
+	// DefaultMaxSize is the default maximum size of a rendered template, the size limit of a secret
+	DefaultMaxSize = corev1.MaxSecretSize
+)
+
+// Limits bounds the time and output of a template
+type Limits struct {
+	// Timeout is the maximum time a template may take to execute, defaults to DefaultTimeout
+	Timeout time.Duration
+	// MaxSize is the maximum size of the rendered template in bytes, defaults to DefaultMaxSize
+	MaxSize int
+}
+
+// Options configures how a template is rendered
+type Options struct {
+	// MissingKey controls how missing keys are handled, defaults to MissingKeyDefault
+	MissingKey MissingKey
+	// Limits bounds the execution of the template
+	Limits Limits
+}
+
+// Error is returned when rendering fails because of the template itself, rather than its input
+type Error struct {
+	// Key is the missing key the template referred to, if any
+	Key string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
+// missingKeyPattern matches the error text/template returns for a missing map key
+var missingKeyPattern = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
 // RenderTemplate executes a Go template with the provided secret data
-// The secret data is made available as .Ref.<key> in the template
+// The secret data is made available as .Ref.<key> in the template, together with the functions of FuncMap
 func RenderTemplate(templateStr string, secretData map[string][]byte) ([]byte, error) {
+	return Render(templateStr, TemplateData{
+		Ref: NewInputValues(secretData),
+	}, Options{})
+}
+
+// Render executes a Go template with the provided template data
+func Render(templateStr string, data TemplateData, opts Options) ([]byte, error) {
 	if templateStr == "" {
-		return nil, fmt.Errorf("template string cannot be empty")
+		return nil, &Error{Err: fmt.Errorf("template string cannot be empty")}
 	}
 
-	// Convert []byte values to strings for easier template usage
-	refData := make(map[string]string)
-	for key, value := range secretData {
-		refData[key] = string(value)
+	missingKey := opts.MissingKey
+	if missingKey == "" {
+		missingKey = MissingKeyDefault
 	}
 
-	data := TemplateData{
-		Ref: refData,
+	timeout := opts.Limits.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	maxSize := opts.Limits.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
 	// Parse and execute the template
-	tmpl, err := template.New("secret").Parse(templateStr)
+	funcs := FuncMap()
+	funcs[budgetFunc] = func() (string, error) {
+		return "", ctx.Err()
+	}
+	tmpl, err := template.New("secret").Funcs(funcs).Option("missingkey=" + string(missingKey)).Parse(templateStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template: %w", err)
+		return nil, &Error{Err: fmt.Errorf("failed to parse template: %w", err)}
 	}
+	if err := addBudgetChecks(tmpl, funcs); err != nil {
+		return nil, &Error{Err: fmt.Errorf("failed to parse template: %w", err)}
+	}
+
+	// text/template can not be cancelled, so the template is executed in the background. After the timeout the
+	// output rejects any further writes, and every loop iteration and template call fails, which stops the template.
+	out := &limitedWriter{ctx: ctx, maxSize: maxSize}
+	done := make(chan error, 1)
+	go func() {
+		done <- tmpl.Execute(out, data)
+	}()
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, fmt.Errorf("failed to execute template: %w", err)
+	select {
+	case err := <-done:
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &Error{Err: fmt.Errorf("failed to execute template: exceeded the timeout of %s", timeout)}
+		}
+		if err != nil {
+			if errors.Is(err, errOutputTooLarge) {
+				return nil, &Error{Err: fmt.Errorf("failed to execute template: output exceeds the limit of %d bytes", maxSize)}
+			}
+			if match := missingKeyPattern.FindStringSubmatch(err.Error()); match != nil {
+				return nil, &Error{Key: match[1], Err: fmt.Errorf("failed to execute template: missing key %q: %w", match[1], err)}
+			}
+			return nil, &Error{Err: fmt.Errorf("failed to execute template: %w", err)}
+		}
+	case <-ctx.Done():
+		return nil, &Error{Err: fmt.Errorf("failed to execute template: exceeded the timeout of %s", timeout)}
 	}
 
-	return buf.Bytes(), nil
+	return out.buf.Bytes(), nil
+}
+
+// budgetFunc is the function that is called on every loop iteration and template call, it fails once the template
+// ran out of time
+const budgetFunc = "_budget"
+
+// addBudgetChecks calls the budget function at the start of every range body and of every template, so templates
+// that loop or recurse without producing output still stop after the timeout
+func addBudgetChecks(tmpl *template.Template, funcs template.FuncMap) error {
+	check, err := template.New("budget").Funcs(funcs).Parse("{{" + budgetFunc + "}}")
+	if err != nil {
+		return err
+	}
+	action := check.Tree.Root.Nodes[0]
+
+	var walk func(list *parse.ListNode)
+	walk = func(list *parse.ListNode) {
+		if list == nil {
+			return
+		}
+		for _, node := range list.Nodes {
+			switch node := node.(type) {
+			case *parse.IfNode:
+				walk(node.List)
+				walk(node.ElseList)
+			case *parse.WithNode:
+				walk(node.List)
+				walk(node.ElseList)
+			case *parse.RangeNode:
+				walk(node.List)
+				walk(node.ElseList)
+				node.List.Nodes = append([]parse.Node{action}, node.List.Nodes...)
+			case *parse.ListNode:
+				walk(node)
+			}
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		walk(t.Tree.Root)
+		t.Tree.Root.Nodes = append([]parse.Node{action}, t.Tree.Root.Nodes...)
+	}
+	return nil
+}
+
+// errOutputTooLarge is returned by limitedWriter when the output exceeds its maximum size
+var errOutputTooLarge = errors.New("template output too large")
+
+// limitedWriter buffers the output of a template, up to a maximum size and until its context is done
+type limitedWriter struct {
+	ctx     context.Context
+	maxSize int
+	buf     bytes.Buffer
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if w.buf.Len()+len(p) > w.maxSize {
+		return 0, errOutputTooLarge
+	}
+	return w.buf.Write(p)
 }
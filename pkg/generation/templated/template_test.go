@@ -1,7 +1,10 @@
 package templated
 
 import (
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -258,3 +261,135 @@ data:
 		assert.Equal(t, expected, string(result))
 	})
 }
+
+func TestRender(t *testing.T) {
+	data := TemplateData{
+		Ref: map[string]string{"user": "app"},
+		Inputs: map[string]map[string]string{
+			"db":   {"password": "db-pass"},
+			"smtp": {"password": "smtp-pass"},
+		},
+	}
+
+	result, err := Render("{{.Ref.user}}:{{.Inputs.db.password}} {{.Inputs.smtp.password}}", data, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "app:db-pass smtp-pass", string(result))
+}
+
+func TestRenderMissingKey(t *testing.T) {
+	data := TemplateData{
+		Ref: map[string]string{"user": "app"},
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		missingKey  MissingKey
+		expected    string
+		expectedKey string
+	}{
+		{
+			name:     "default policy renders no value",
+			template: "{{.Ref.typo}}",
+			expected: "<no value>",
+		},
+		{
+			name:       "explicit default policy renders no value",
+			template:   "{{.Ref.typo}}",
+			missingKey: MissingKeyDefault,
+			expected:   "<no value>",
+		},
+		{
+			name:       "zero policy renders an empty string",
+			template:   "[{{.Ref.typo}}]",
+			missingKey: MissingKeyZero,
+			expected:   "[]",
+		},
+		{
+			name:        "error policy fails and names the key",
+			template:    "{{.Ref.user}}:{{.Ref.typo}}",
+			missingKey:  MissingKeyError,
+			expectedKey: "typo",
+		},
+		{
+			name:        "error policy fails on a missing input alias",
+			template:    "{{.Inputs.db.password}}",
+			missingKey:  MissingKeyError,
+			expectedKey: "db",
+		},
+		{
+			name:       "error policy allows optional keys through index",
+			template:   `{{ index .Ref "port" | default "5432" }}`,
+			missingKey: MissingKeyError,
+			expected:   "5432",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Render(tt.template, data, Options{MissingKey: tt.missingKey})
+
+			if tt.expectedKey != "" {
+				require.Error(t, err)
+				var templateErr *Error
+				require.ErrorAs(t, err, &templateErr)
+				assert.Equal(t, tt.expectedKey, templateErr.Key)
+				assert.Contains(t, err.Error(), tt.expectedKey)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestRenderLimits(t *testing.T) {
+	t.Run("output within the limit is rendered", func(t *testing.T) {
+		result, err := Render("{{range 10}}0123456789{{end}}", TemplateData{}, Options{Limits: Limits{MaxSize: 100}})
+		require.NoError(t, err)
+		assert.Len(t, result, 100)
+	})
+
+	t.Run("output over the limit fails", func(t *testing.T) {
+		_, err := Render("{{range 11}}0123456789{{end}}", TemplateData{}, Options{Limits: Limits{MaxSize: 100}})
+		var templateErr *Error
+		require.ErrorAs(t, err, &templateErr)
+		assert.Contains(t, err.Error(), "exceeds the limit of 100 bytes")
+	})
+
+	t.Run("output is limited to the size of a secret by default", func(t *testing.T) {
+		_, err := Render(`{{range 1048577}}x{{end}}`, TemplateData{}, Options{Limits: Limits{Timeout: time.Minute}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds the limit of 1048576 bytes")
+	})
+
+	t.Run("templates running over the timeout fail", func(t *testing.T) {
+		_, err := Render(`{{range 1000}}{{bcrypt "password"}}{{end}}`, TemplateData{}, Options{Limits: Limits{Timeout: 10 * time.Millisecond}})
+		var templateErr *Error
+		require.ErrorAs(t, err, &templateErr)
+		assert.Contains(t, err.Error(), "exceeded the timeout of 10ms")
+	})
+
+	for _, tt := range []struct {
+		name     string
+		template string
+	}{
+		{name: "loops without output", template: `{{range 1000000000}}{{range 1000000000}}{{end}}{{end}}x`},
+		{name: "recursion without output", template: `{{define "a"}}{{template "a" .}}{{template "a" .}}{{end}}{{template "a" .}}`},
+		{name: "loops in defined templates", template: `{{define "a"}}{{range 1000000000}}{{end}}{{end}}{{template "a" .}}`},
+	} {
+		t.Run(tt.name+" stop after the timeout", func(t *testing.T) {
+			_, err := Render(tt.template, TemplateData{}, Options{Limits: Limits{Timeout: 50 * time.Millisecond}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "exceeded the timeout of 50ms")
+			// The template is executed in its own goroutine, which must have returned
+			assert.Eventually(t, func() bool { return !executingTemplates() }, time.Second, 10*time.Millisecond, "template execution is still running")
+		})
+	}
+}
+
+// executingTemplates reports whether any goroutine is still executing a template
+func executingTemplates() bool {
+	buf := make([]byte, 1<<20)
+	return strings.Contains(string(buf[:runtime.Stack(buf, true)]), "text/template.(*state).walk")
+}